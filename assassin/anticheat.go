@@ -0,0 +1,181 @@
+package assassin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Penalty is what happens to a player caught cheating.
+type Penalty int
+
+const (
+	// IgnorePenalty : the offending message is ignored, so cannot launch or counter an attack.
+	IgnorePenalty Penalty = iota
+	// WarnPenalty : the offending message is ignored, and the player warned privately.
+	WarnPenalty
+	// EliminatePenalty : the player is disqualified, and eliminated from the game.
+	EliminatePenalty
+)
+
+var penalties = []string{"ignore", "warn", "eliminate"}
+
+func (p Penalty) String() string {
+	if p >= 0 && int(p) < len(penalties) {
+		return penalties[p]
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the penalty by name, for config files.
+func (p Penalty) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText decodes a penalty by name: ignore, warn or eliminate.
+func (p *Penalty) UnmarshalText(b []byte) error {
+	for i, s := range penalties {
+		if string(b) == s {
+			*p = Penalty(i)
+			return nil
+		}
+	}
+	return &PenaltyError{string(b)}
+}
+
+// PenaltyError is returned when decoding an unknown penalty.
+type PenaltyError struct {
+	Value string
+}
+
+func (e PenaltyError) Error() string {
+	return fmt.Sprintf("Unknown penalty %q", e.Value)
+}
+
+// Cheat is the reason a player's message was flagged.
+type Cheat int
+
+const (
+	// LongMessageCheat : the message had more words than allowed. At worst the player is warned.
+	LongMessageCheat Cheat = iota
+	// FishingCheat : the message said more of the game's KillWords than allowed.
+	FishingCheat
+	// AttackRateCheat : the player launched attacks more often than allowed.
+	AttackRateCheat
+)
+
+func (c Cheat) String() string {
+	switch c {
+	case LongMessageCheat:
+		return "long message"
+	case FishingCheat:
+		return "fishing"
+	case AttackRateCheat:
+		return "attack rate"
+	}
+	return "unknown"
+}
+
+/*
+AntiCheat catches players fishing for KillWords, by pasting word dumps into chat or attacking at random.
+A zero limit is not checked. Only messages saying the speaker's own KillWord are checked, as only they can launch or counter an attack,
+and assassinations always count, as saying a contract's KillWord can only hurt the speaker.
+Set it on a game with Game.SetAntiCheat.
+*/
+type AntiCheat struct {
+	// MaxWords is the most words a message may have.
+	MaxWords int
+	// MaxKillWords is the most KillWords of the game's word list a message may say.
+	MaxKillWords int
+	// MaxAttacks is the most attacks a player may launch within Window.
+	MaxAttacks int
+	Window     time.Duration
+	// Penalty is what happens to a player whose message is flagged. Long messages are never penalised beyond a warning.
+	Penalty Penalty
+	// Words are the KillWords checked for fishing. If nil, the words of the game's WordList are used.
+	Words []string
+}
+
+// antiCheatJSON is the config file encoding of AntiCheat, with a window like "1m".
+type antiCheatJSON struct {
+	MaxWords     int      `json:"maxWords"`
+	MaxKillWords int      `json:"maxKillWords"`
+	MaxAttacks   int      `json:"maxAttacks"`
+	Window       string   `json:"window,omitempty"`
+	Penalty      Penalty  `json:"penalty"`
+	Words        []string `json:"words,omitempty"`
+}
+
+// MarshalJSON encodes the AntiCheat for config files.
+func (ac AntiCheat) MarshalJSON() ([]byte, error) {
+	var aj = antiCheatJSON{ac.MaxWords, ac.MaxKillWords, ac.MaxAttacks, "", ac.Penalty, ac.Words}
+	if ac.Window != 0 {
+		aj.Window = ac.Window.String()
+	}
+	return json.Marshal(aj)
+}
+
+// UnmarshalJSON decodes an AntiCheat from a config file.
+func (ac *AntiCheat) UnmarshalJSON(b []byte) error {
+	var aj antiCheatJSON
+	if err := json.Unmarshal(b, &aj); err != nil {
+		return err
+	}
+	var w time.Duration
+	if aj.Window != "" {
+		var err error
+		if w, err = time.ParseDuration(aj.Window); err != nil {
+			return err
+		}
+	}
+	*ac = AntiCheat{aj.MaxWords, aj.MaxKillWords, aj.MaxAttacks, w, aj.Penalty, aj.Words}
+	return nil
+}
+
+// wordLister is implemented by WordGenerators that can list all their words.
+type wordLister interface {
+	Words() []string
+}
+
+// check text said in game g, reporting whether and why it is flagged.
+func (ac *AntiCheat) check(g *Game, text string) (Cheat, bool) {
+	if ac.MaxWords > 0 && len(strings.Fields(text)) > ac.MaxWords {
+		return LongMessageCheat, true
+	}
+	if ac.MaxKillWords > 0 {
+		var words = ac.Words
+		if wl, ok := g.kwg.(wordLister); ok && words == nil {
+			words = wl.Words()
+		}
+		var (
+			n    int
+			says = g.sayer(text)
+		)
+		for _, w := range words {
+			if says(w) {
+				if n++; n > ac.MaxKillWords {
+					return FishingCheat, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+/*
+recent returns the launch times ls that count against the attack limit at now: those within the window, and no more than the limit.
+Older launches are dropped, so the history kept for each player stays short.
+*/
+func (ac *AntiCheat) recent(ls []time.Time, now time.Time) []time.Time {
+	var kept []time.Time
+	for _, l := range ls {
+		if ac.Window <= 0 || now.Sub(l) < ac.Window {
+			kept = append(kept, l)
+		}
+	}
+	if len(kept) > ac.MaxAttacks {
+		kept = kept[len(kept)-ac.MaxAttacks:]
+	}
+	return kept
+}
@@ -0,0 +1,145 @@
+package assassin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAntiCheatJSON(t *testing.T) {
+	var ac AntiCheat
+	var err = json.Unmarshal([]byte(`{"maxWords": 20, "maxAttacks": 2, "window": "1m", "penalty": "warn"}`), &ac)
+	if err != nil || ac.MaxWords != 20 || ac.MaxAttacks != 2 || ac.Window != time.Minute || ac.Penalty != WarnPenalty {
+		t.Error("Unexpected config", ac, err)
+	}
+	if err := json.Unmarshal([]byte(`{"penalty": "shame"}`), &ac); err == nil {
+		t.Error("Expected penalty error")
+	}
+	var b, _ = json.Marshal(AntiCheat{MaxKillWords: 3, Penalty: EliminatePenalty})
+	if string(b) != `{"maxWords":0,"maxKillWords":3,"maxAttacks":0,"penalty":"eliminate"}` {
+		t.Error("Unexpected encoding", string(b))
+	}
+}
+
+// cheatRun starts a game of Ace, Bee and Cee, in that order, checked by ac.
+func cheatRun(ac *AntiCheat, words ...string) (*gameRun, *[]Event) {
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList(words, nil), nil)
+	g.SetAntiCheat(ac)
+	var r = newGameRun(g)
+	var evs = new([]Event)
+	r.publish = func(ev Event) { *evs = append(*evs, ev) }
+	r.begin([]ID{1, 2, 3})
+	*evs = nil
+	return r, evs
+}
+
+func TestAntiCheat(t *testing.T) {
+	t.Run("LongMessage", func(t *testing.T) {
+		var r, evs = cheatRun(&AntiCheat{MaxWords: 3, Penalty: WarnPenalty}, "apple", "banana", "cherry")
+		var p, _ = r.g.GetPlayer(1)
+		r.handleTalk(Talk{Game: 1, Speaker: 1, Text: "I really do like " + p.KillWord})
+		if len(*evs) != 1 {
+			t.Fatal("Unexpected events", *evs)
+		}
+		if ev, ok := (*evs)[0].(CheatDetected); !ok || ev.Player.ID != 1 || ev.Cheat != LongMessageCheat || ev.Penalty != WarnPenalty {
+			t.Error("Unexpected event", (*evs)[0])
+		}
+		*evs = nil
+		r.handleTalk(Talk{Game: 1, Speaker: 1, Text: p.KillWord})
+		if _, ok := (*evs)[0].(AttackLaunched); len(*evs) != 1 || !ok {
+			t.Error("Unexpected events", *evs)
+		}
+	})
+	t.Run("LongMessageEliminate", func(t *testing.T) {
+		var r, evs = cheatRun(&AntiCheat{MaxWords: 3, Penalty: EliminatePenalty}, "apple", "banana", "cherry")
+		var p, _ = r.g.GetPlayer(1)
+		// talk not saying the speaker's KillWord is not checked
+		r.handleTalk(Talk{Game: 1, Speaker: 1, Text: "a long message about nothing much"})
+		if len(*evs) != 0 {
+			t.Error("Unexpected events", *evs)
+		}
+		r.handleTalk(Talk{Game: 1, Speaker: 1, Text: "I really do like " + p.KillWord})
+		if ev, ok := (*evs)[0].(CheatDetected); len(*evs) != 1 || !ok || ev.Cheat != LongMessageCheat || ev.Penalty != WarnPenalty {
+			t.Error("Unexpected events", *evs)
+		}
+		if r.alive != 3 {
+			t.Error("Alive", r.alive, "!= 3")
+		}
+	})
+	t.Run("Fishing", func(t *testing.T) {
+		var words = []string{"apple", "banana", "cherry", "damson", "elder"}
+		var r, evs = cheatRun(&AntiCheat{MaxKillWords: 2, Penalty: EliminatePenalty}, words...)
+		// every word but the KillWord of Cee, Ace's contract
+		var c, _ = r.g.GetPlayer(3)
+		var text string
+		for _, w := range words {
+			if w != c.KillWord {
+				text += w + ", "
+			}
+		}
+		r.handleTalk(Talk{Game: 1, Speaker: 1, Text: text})
+		if ev, ok := (*evs)[0].(CheatDetected); !ok || ev.Cheat != FishingCheat {
+			t.Error("Unexpected event", (*evs)[0])
+		}
+		if ev, ok := (*evs)[1].(PlayerEliminated); !ok || ev.Player.ID != 1 || ev.By.ID != 1 || ev.Method != DisqualifiedMethod {
+			t.Error("Unexpected event", (*evs)[1])
+		}
+		if ev, ok := (*evs)[2].(TargetAssigned); !ok || ev.Player.ID != 3 || ev.Target.ID != 2 {
+			t.Error("Unexpected event", (*evs)[2])
+		}
+		if r.alive != 2 {
+			t.Error("Alive", r.alive, "!= 2")
+		}
+	})
+	t.Run("Assassination", func(t *testing.T) {
+		// saying a contract's KillWord still counts
+		var r, evs = cheatRun(&AntiCheat{MaxWords: 1}, "apple", "banana", "cherry")
+		var c, _ = r.g.GetPlayer(1)
+		r.handleTalk(Talk{Game: 1, Speaker: 2, Text: "I like " + c.KillWord})
+		if _, ok := (*evs)[0].(PlayerAssassinated); !ok {
+			t.Error("Unexpected event", (*evs)[0])
+		}
+	})
+	t.Run("AttackRate", func(t *testing.T) {
+		var r, evs = cheatRun(&AntiCheat{MaxAttacks: 2, Window: time.Minute}, "apple", "banana", "cherry")
+		var p, _ = r.g.GetPlayer(1)
+		var now = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		r.now = func() time.Time { return now }
+		var attack = func() Event {
+			*evs = nil
+			r.handleTalk(Talk{Game: 1, Speaker: 1, Text: p.KillWord})
+			return (*evs)[0]
+		}
+		for i := 0; i < 2; i++ {
+			if ev, ok := attack().(AttackLaunched); !ok {
+				t.Error("Unexpected event", ev)
+			}
+			now = now.Add(20 * time.Second)
+		}
+		if ev, ok := attack().(CheatDetected); !ok || ev.Cheat != AttackRateCheat || ev.Penalty != IgnorePenalty || len(*evs) != 1 {
+			t.Error("Unexpected events", *evs)
+		}
+		now = now.Add(20 * time.Second)
+		if ev, ok := attack().(AttackLaunched); !ok {
+			t.Error("Unexpected event", ev)
+		}
+		if ls := r.launches[1]; len(ls) != 2 || !ls[1].Equal(now) {
+			t.Error("Unexpected launch history", ls)
+		}
+		// the history is saved, so a resumed game keeps to the limit
+		var b, err = json.Marshal(r.snapshot())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var s Snapshot
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatal(err)
+		}
+		r = restoreGameRun(&s, NewWordList([]string{"damson"}, nil))
+		r.publish = func(ev Event) { *evs = append(*evs, ev) }
+		r.now = func() time.Time { return now }
+		if ev, ok := attack().(CheatDetected); !ok || ev.Cheat != AttackRateCheat {
+			t.Error("Unexpected events after resume", *evs)
+		}
+	})
+}
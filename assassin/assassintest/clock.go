@@ -0,0 +1,99 @@
+/*
+Package assassintest provides fakes for testing games offline and in a hurry.
+
+A Clock stands in for the system clock, so that attack windows pass when the test says so:
+
+	var clock = assassintest.NewClock(time.Now())
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, assassin.FixedTiming(time.Minute), clock)
+	...
+	clock.BlockUntil(1) // the attack is timed
+	clock.Advance(time.Minute)
+*/
+package assassintest
+
+import (
+	"sync"
+	"time"
+)
+
+// waiter is a channel waiting for the time to reach at.
+type waiter struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// Clock is a fake clock, only moving when advanced. It is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []waiter
+}
+
+// NewClock creates a new Clock instance, set to now.
+func NewClock(now time.Time) *Clock {
+	var c = &Clock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the clock's time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel sent the clock's time once it has been advanced by d.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var w = waiter{c.now.Add(d), make(chan time.Time, 1)}
+	if d <= 0 {
+		w.c <- c.now
+		return w.c
+	}
+	c.waiters = append(c.waiters, w)
+	c.cond.Broadcast()
+	return w.c
+}
+
+// Advance the clock by d, firing the channels of any After calls now due.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	var waiting = c.waiters[:0]
+	for _, w := range c.waiters {
+		if w.at.After(c.now) {
+			waiting = append(waiting, w)
+		} else {
+			w.c <- c.now
+		}
+	}
+	c.waiters = waiting
+	c.cond.Broadcast()
+}
+
+/*
+Waiters returns the number of After calls not yet due.
+Calls whose channel is no longer received from still count until due, such as the timer of a stopped game's attack,
+or a quiet spell restarted by an elimination, so count on from the waiters already there.
+*/
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+/*
+BlockUntil waits until there are at least n After calls not yet due, counted as by Waiters.
+Use it to be sure the engine has timed an attack before advancing past it.
+*/
+func (c *Clock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.waiters) < n {
+		c.cond.Wait()
+	}
+}
@@ -0,0 +1,48 @@
+package assassintest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClock(t *testing.T) {
+	var start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	var c = NewClock(start)
+	if now := <-c.After(0); !now.Equal(start) || c.Waiters() != 0 {
+		t.Error("Unexpected immediate After", now, c.Waiters())
+	}
+	var minute, hour = c.After(time.Minute), c.After(time.Hour)
+	var done = make(chan struct{})
+	go func() { c.BlockUntil(2); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("BlockUntil not released", c.Waiters())
+	}
+	c.Advance(time.Minute)
+	select {
+	case now := <-minute:
+		if !now.Equal(start.Add(time.Minute)) {
+			t.Error("Unexpected time", now)
+		}
+	default:
+		t.Error("After not fired")
+	}
+	select {
+	case <-hour:
+		t.Error("After fired early")
+	default:
+	}
+	t.Run("Abandoned", func(t *testing.T) {
+		// hour is no longer received from, but counts until due
+		if n := c.Waiters(); n != 1 {
+			t.Error("Waiters", n, "!= 1")
+		}
+		c.After(time.Minute)
+		c.BlockUntil(2)
+		c.Advance(time.Hour)
+		if n := c.Waiters(); n != 0 {
+			t.Error("Waiters", n, "!= 0")
+		}
+	})
+}
@@ -0,0 +1,19 @@
+package assassin
+
+import "time"
+
+/*
+Clock tells the engine the time, and when attacks are due.
+The system clock is used by default; tests can use a fake clock they advance by hand (see package assassintest).
+*/
+type Clock interface {
+	Now() time.Time
+	// After waits for duration d to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the Clock of package time.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
@@ -0,0 +1,399 @@
+package assassin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinPlayers is the fewest players needed to start a game from a lobby.
+const MinPlayers = 2
+
+/*
+Commands is a chat front end for a GameEngine.
+Players sign up to a lobby for each channel, and an admin starts a game with everyone signed up.
+Commands are chat messages starting with a prefix, "!" by default:
+
+	join, leave   sign up to, or drop out of, the channel's next game; "join red" signs up to team red
+	list          list the players signed up
+	start, stop   start, or stop, the channel's game (admins only)
+	status        list the players still alive
+	target        privately repeat the speaker's target and KillWord
+	help          list the commands
+
+All other talk is passed on to the engine, addressed to the channel's running game if it has one.
+Commands are never passed on, so cannot trigger a KillWord. Games are given IDs by GameEngine.NewID.
+*/
+type Commands struct {
+	e        *GameEngine
+	tpl      Lang
+	words    func() WordGenerator
+	mu       sync.Mutex
+	prefix   string
+	admin    func(t Talk) bool
+	settings Settings
+	lobbies  map[string]*lobby
+}
+
+// lobby holds the sign ups, and any running game, for a channel.
+type lobby struct {
+	players  map[ID]string
+	teams    Teams
+	game     ID
+	render   *MessageRenderer
+	assigned map[ID]TargetAssigned
+}
+
+// ids returns the IDs of players signed up, in ascending order.
+func (l *lobby) ids() []ID {
+	var ids = make([]ID, 0, len(l.players))
+	for id := range l.players {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// names returns the names of players signed up, with their teams, in ID order.
+func (l *lobby) names() []string {
+	var ids = l.ids()
+	var ns = make([]string, 0, len(ids))
+	for _, id := range ids {
+		if t, ok := l.teams[id]; ok {
+			ns = append(ns, fmt.Sprintf("%v (%v)", l.players[id], t))
+		} else {
+			ns = append(ns, l.players[id])
+		}
+	}
+	return ns
+}
+
+// alive returns the names of players still alive in the running game, in ID order.
+func (l *lobby) alive() []string {
+	var ids = make([]ID, 0, len(l.assigned))
+	for id, a := range l.assigned {
+		if a.Player.Alive {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var ns = make([]string, 0, len(ids))
+	for _, id := range ids {
+		ns = append(ns, l.assigned[id].Player.Name)
+	}
+	return ns
+}
+
+// lobbyGame follows the events of a lobby's running game, rendering them to the lobby's channel.
+type lobbyGame struct {
+	c *Commands
+	l *lobby
+	r *MessageRenderer
+}
+
+func (s *lobbyGame) Handle(ev Event) {
+	s.c.mu.Lock()
+	switch ev := ev.(type) {
+	case TargetAssigned:
+		s.l.assigned[ev.Player.ID] = ev
+	case PlayerEliminated:
+		var a = s.l.assigned[ev.Player.ID]
+		a.Player = ev.Player
+		s.l.assigned[ev.Player.ID] = a
+	}
+	s.c.mu.Unlock()
+	s.r.Handle(ev)
+}
+
+/*
+NewCommands creates a new Commands instance running games on e.
+Each game started is given a fresh WordGenerator from words.
+*/
+func NewCommands(e *GameEngine, tpl Lang, words func() WordGenerator) *Commands {
+	return &Commands{
+		e:       e,
+		tpl:     tpl,
+		words:   words,
+		prefix:  "!",
+		lobbies: make(map[string]*lobby),
+	}
+}
+
+// SetPrefix sets the prefix marking talk as a command.
+func (c *Commands) SetPrefix(p string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prefix = p
+}
+
+// SetAdmin sets the check for whether the speaker of t may start and stop games. Use nil to allow everyone.
+func (c *Commands) SetAdmin(f func(t Talk) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.admin = f
+}
+
+// SetMatcher sets the Matcher of games started. Use nil for DefaultMatcher.
+func (c *Commands) SetMatcher(m Matcher) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings.Matcher = m
+}
+
+// SetAntiCheat sets the checks for players fishing for KillWords in games started. Use nil to turn them off.
+func (c *Commands) SetAntiCheat(ac *AntiCheat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ac != nil {
+		var cp = *ac
+		ac = &cp
+	}
+	c.settings.AntiCheat = ac
+}
+
+// SetIntel sets the quiet spell after which players in games started are given hints (see Game.SetIntel).
+func (c *Commands) SetIntel(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings.Intel = d
+}
+
+// with runs f on the lobby for channel ch, holding the lock.
+func (c *Commands) with(ch string, f func(l *lobby)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var l, ok = c.lobbies[ch]
+	if !ok {
+		l = &lobby{players: make(map[ID]string), teams: make(Teams)}
+		c.lobbies[ch] = l
+	}
+	f(l)
+}
+
+func (c *Commands) isAdmin(t Talk) bool {
+	c.mu.Lock()
+	var f = c.admin
+	c.mu.Unlock()
+	return f == nil || f(t)
+}
+
+func speaker(t Talk) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fmt.Sprint(t.Speaker)
+}
+
+/*
+Handle a chat message, replying to the channel through reply.
+Commands are carried out; all other talk is passed on to the engine's games.
+*/
+func (c *Commands) Handle(t Talk, reply MessageHandler) {
+	c.mu.Lock()
+	var prefix = c.prefix
+	c.mu.Unlock()
+	var args []string
+	if strings.HasPrefix(t.Text, prefix) {
+		args = strings.Fields(strings.TrimPrefix(t.Text, prefix))
+	}
+	if len(args) == 0 {
+		c.mu.Lock()
+		if l, ok := c.lobbies[t.Channel]; ok && t.Game == 0 && l.game != 0 {
+			// Nb. a game started without a channel has none to route talk by, so talk is addressed to it.
+			t.Game = l.game
+		}
+		c.mu.Unlock()
+		c.e.IncomingTalk(t)
+		return
+	}
+	var cmd = strings.ToLower(args[0])
+	switch cmd {
+	case "join":
+		c.join(t, strings.Join(args[1:], " "), reply)
+	case "leave":
+		c.leave(t, reply)
+	case "list":
+		c.list(t, reply)
+	case "start":
+		c.start(t, reply)
+	case "stop":
+		c.stop(t, strings.Join(args[1:], " "), reply)
+	case "status":
+		c.status(t, reply)
+	case "target":
+		c.target(t, reply)
+	case "help":
+		var cs = []string{"join", "leave", "list", "start", "stop", "status", "target", "help"}
+		for i := range cs {
+			cs[i] = prefix + cs[i]
+		}
+		reply.Announce(c.tpl.Fmt(c.tpl.CH, strings.Join(cs, ", ")))
+	default:
+		reply.Announce(c.tpl.Fmt(c.tpl.CU, cmd, prefix))
+	}
+}
+
+// join signs the speaker up to the next game, on the given team if any.
+func (c *Commands) join(t Talk, team string, reply MessageHandler) {
+	var s string
+	c.with(t.Channel, func(l *lobby) {
+		var _, ok = l.players[t.Speaker]
+		switch {
+		case l.game != 0:
+			s = c.tpl.EIP
+		case ok && (team == "" || l.teams[t.Speaker] == team):
+			s = c.tpl.Fmt(c.tpl.CAJ, speaker(t))
+		case team != "":
+			l.players[t.Speaker] = speaker(t)
+			l.teams[t.Speaker] = team
+			s = c.tpl.Fmt(c.tpl.CJT, speaker(t), team)
+		default:
+			l.players[t.Speaker] = speaker(t)
+			s = c.tpl.Fmt(c.tpl.CJ, speaker(t))
+		}
+	})
+	reply.Announce(s)
+}
+
+func (c *Commands) leave(t Talk, reply MessageHandler) {
+	var s string
+	c.with(t.Channel, func(l *lobby) {
+		var _, ok = l.players[t.Speaker]
+		switch {
+		case l.game != 0:
+			s = c.tpl.EIP
+		case !ok:
+			s = c.tpl.Fmt(c.tpl.CNJ, speaker(t))
+		default:
+			delete(l.players, t.Speaker)
+			delete(l.teams, t.Speaker)
+			s = c.tpl.Fmt(c.tpl.CL, speaker(t))
+		}
+	})
+	reply.Announce(s)
+}
+
+func (c *Commands) list(t Talk, reply MessageHandler) {
+	var s string
+	c.with(t.Channel, func(l *lobby) {
+		if len(l.players) == 0 {
+			s = c.tpl.CNP
+		} else {
+			s = c.tpl.Fmt(c.tpl.CP, strings.Join(l.names(), ", "))
+		}
+	})
+	reply.Announce(s)
+}
+
+func (c *Commands) start(t Talk, reply MessageHandler) {
+	if !c.isAdmin(t) {
+		reply.Announce(c.tpl.Fmt(c.tpl.CA, "start"))
+		return
+	}
+	var (
+		g   *Game
+		sub Subscriber
+		s   string
+	)
+	c.with(t.Channel, func(l *lobby) {
+		switch {
+		case l.game != 0:
+			s = c.tpl.EIP
+		case len(l.players) < MinPlayers:
+			s = c.tpl.Fmt(c.tpl.CFP, MinPlayers)
+		case len(l.teams) > 0 && l.teams.Over(l.ids()):
+			s = c.tpl.CFT
+		default:
+			g = NewGame(c.e.NewID(), l.players, c.words(), nil)
+			g.settings = c.settings
+			if len(l.teams) > 0 {
+				var ts = make(Teams, len(l.teams))
+				for id, t := range l.teams {
+					ts[id] = t
+				}
+				g.SetTargetAssigner(ts)
+			}
+			if t.Channel != "" {
+				g.SetChannels(t.Channel)
+			}
+			l.game = g.ID
+			l.render = NewMessageRenderer(c.tpl, reply)
+			l.assigned = make(map[ID]TargetAssigned)
+			sub = ForGame(g.ID, &lobbyGame{c, l, l.render})
+		}
+	})
+	if g == nil {
+		reply.Announce(s)
+		return
+	}
+	c.e.Subscribe(sub)
+	go func() {
+		if _, err := c.e.Run(g); err != nil {
+			reply.Announce(err.Error())
+		}
+		c.e.Unsubscribe(sub)
+		c.with(t.Channel, func(l *lobby) {
+			l.game = 0
+			l.render = nil
+			l.assigned = nil
+		})
+	}()
+}
+
+func (c *Commands) stop(t Talk, reason string, reply MessageHandler) {
+	if !c.isAdmin(t) {
+		reply.Announce(c.tpl.Fmt(c.tpl.CA, "stop"))
+		return
+	}
+	var id ID
+	c.with(t.Channel, func(l *lobby) { id = l.game })
+	if id == 0 {
+		reply.Announce(c.tpl.ENR)
+		return
+	}
+	if err := c.e.Stop(id, reason); err != nil {
+		reply.Announce(err.Error())
+	}
+}
+
+func (c *Commands) status(t Talk, reply MessageHandler) {
+	var s string
+	c.with(t.Channel, func(l *lobby) {
+		switch {
+		case l.game != 0:
+			s = c.tpl.Fmt(c.tpl.CS, strings.Join(l.alive(), ", "))
+		case len(l.players) == 0:
+			s = c.tpl.CNP
+		default:
+			s = c.tpl.Fmt(c.tpl.CP, strings.Join(l.names(), ", "))
+		}
+	})
+	reply.Announce(s)
+}
+
+func (c *Commands) target(t Talk, reply MessageHandler) {
+	var (
+		a  TargetAssigned
+		ok bool
+		r  *MessageRenderer
+		s  string
+	)
+	c.with(t.Channel, func(l *lobby) {
+		if l.game == 0 {
+			s = c.tpl.ENR
+			return
+		}
+		if a, ok = l.assigned[t.Speaker]; !ok {
+			s = c.tpl.Fmt(c.tpl.CNJ, speaker(t))
+			return
+		}
+		r = l.render
+	})
+	if r == nil {
+		reply.Announce(s)
+		return
+	}
+	r.notifyStatus(a.Player, a.Target)
+}
@@ -0,0 +1,100 @@
+package assassin
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCommands(t *testing.T) {
+	var mh = newTestMessageHandler(t)
+	var e = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), testClock())
+	var c = NewCommands(e, LangEn, func() WordGenerator { return NewWordList([]string{"kw"}, nil) })
+	c.SetAdmin(func(t Talk) bool { return t.Speaker == 1 })
+	var a, b = Player{ID: 1, Name: "Ace"}, Player{ID: 2, Name: "Bee"}
+	var say = func(t *testing.T, p Player, s string) {
+		t.Logf("@%v << %v", p.Name, s)
+		go c.Handle(Talk{Channel: "#game", Speaker: p.ID, Name: p.Name, Time: time.Now(), Text: s}, mh)
+	}
+	var rpt = regexp.MustCompile(LangEn.Fmt(LangEn.PT, ".+", "kw"))
+	t.Run("lobby", func(t *testing.T) {
+		mh.set(t)
+		say(t, a, "!list")
+		mh.expect(LangEn.CNP)
+		say(t, a, "!join")
+		mh.expect(LangEn.Fmt(LangEn.CJ, "Ace"))
+		say(t, a, "!start")
+		mh.expect(LangEn.Fmt(LangEn.CFP, MinPlayers))
+		say(t, b, "!JOIN")
+		mh.expect(LangEn.Fmt(LangEn.CJ, "Bee"))
+		say(t, b, "!join")
+		mh.expect(LangEn.Fmt(LangEn.CAJ, "Bee"))
+		say(t, a, "!list")
+		mh.expect(LangEn.Fmt(LangEn.CP, "Ace, Bee"))
+		say(t, b, "!dance")
+		mh.expect(LangEn.Fmt(LangEn.CU, "dance", "!"))
+	})
+	t.Run("admin", func(t *testing.T) {
+		mh.set(t)
+		say(t, b, "!start")
+		mh.expect(LangEn.Fmt(LangEn.CA, "start"))
+		say(t, a, "!stop")
+		mh.expect(LangEn.ENR)
+	})
+	t.Run("game", func(t *testing.T) {
+		mh.set(t)
+		say(t, a, "!start")
+		mh.expect(LangEn.GS, playerRegexp{a, rpt}, playerRegexp{b, rpt})
+		say(t, b, "!leave")
+		mh.expect(LangEn.EIP)
+		say(t, a, "!target")
+		mh.expect(playerRegexp{a, rpt})
+		// commands are not scanned for KillWords
+		say(t, b, "!status kw")
+		mh.expect(LangEn.Fmt(LangEn.CS, "Ace, Bee"))
+		say(t, b, "!stop")
+		mh.expect(LangEn.Fmt(LangEn.CA, "stop"))
+		say(t, a, "!stop bored")
+		mh.expect(LangEn.Fmt(LangEn.GQ, "bored"), LangEn.Fmt(LangEn.GWM, []string{"Ace", "Bee"}))
+	})
+}
+
+// nullMessageHandler discards all messages.
+type nullMessageHandler struct{}
+
+func (nullMessageHandler) Announce(s string) {}
+
+func (nullMessageHandler) Notify(p Player, s string) {}
+
+func TestCommandsShareEngine(t *testing.T) {
+	var (
+		e      = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), testClock())
+		sub    = newTestSubscriber()
+		words  = func() WordGenerator { return NewWordList([]string{"kw"}, nil) }
+		c1, c2 = NewCommands(e, LangEn, words), NewCommands(e, LangEn, words)
+		h      = nullMessageHandler{}
+	)
+	e.Subscribe(sub)
+	// games started without a channel, as from a console
+	for _, c := range []*Commands{c1, c2} {
+		c.Handle(Talk{Speaker: 1, Name: "Ace", Text: "!join"}, h)
+		c.Handle(Talk{Speaker: 2, Name: "Bee", Text: "!join"}, h)
+		c.Handle(Talk{Speaker: 1, Name: "Ace", Text: "!start"}, h)
+	}
+	// each game started, and 2 targets assigned
+	sub.wait(t, 6)
+	var ids = make(map[ID]bool)
+	for _, ev := range sub.events() {
+		ids[ev.GameID()] = true
+	}
+	if len(ids) != 2 {
+		t.Error("Games share an ID", ids)
+	}
+	// talk is addressed to the lobby's game, so Ace is assassinated saying Bee's KillWord
+	c1.Handle(Talk{Speaker: 1, Name: "Ace", Text: "kw"}, h)
+	sub.wait(t, 1)
+	if ev, ok := sub.events()[0].(PlayerAssassinated); !ok || ev.Game != 1 || ev.Player.ID != 1 {
+		t.Error("Unexpected event", ev)
+	}
+	c2.Handle(Talk{Speaker: 1, Name: "Ace", Text: "!stop"}, h)
+}
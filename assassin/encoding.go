@@ -0,0 +1,120 @@
+package assassin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+/*
+SchemaVersion is the version of the JSON encoding of Game and Player.
+It is increased whenever the encoding changes in a way older readers cannot follow.
+*/
+const SchemaVersion = 1
+
+// SchemaVersionError is returned when decoding JSON of an unsupported schema version.
+type SchemaVersionError struct {
+	Version int
+}
+
+func (e SchemaVersionError) Error() string {
+	return fmt.Sprintf("Unsupported schema version %v (supported: %v)", e.Version, SchemaVersion)
+}
+
+// playerJSON is the JSON encoding of a Player, with only their public details.
+type playerJSON struct {
+	ID    ID     `json:"id"`
+	Name  string `json:"name"`
+	Alive bool   `json:"alive"`
+}
+
+// playerState is the JSON encoding of a Player in a Game, with their KillWord and the chain recorded as player IDs.
+type playerState struct {
+	playerJSON
+	KillWord string `json:"killWord,omitempty"`
+	Target   *ID    `json:"target,omitempty"`
+	Contract *ID    `json:"contract,omitempty"`
+}
+
+// gameJSON is the JSON encoding of a Game.
+type gameJSON struct {
+	Version  int           `json:"version"`
+	ID       ID            `json:"id"`
+	Channels []string      `json:"channels,omitempty"`
+	Players  []playerState `json:"players"`
+	Settings *Settings     `json:"settings,omitempty"`
+}
+
+/*
+MarshalJSON encodes the player's public details.
+Their KillWord and place in the chain are secret, only encoded as part of a Game.
+*/
+func (p Player) MarshalJSON() ([]byte, error) {
+	return json.Marshal(playerJSON{p.ID, p.Name, p.Alive})
+}
+
+// UnmarshalJSON decodes a player's public details.
+func (p *Player) UnmarshalJSON(b []byte) error {
+	var pj playerJSON
+	if err := json.Unmarshal(b, &pj); err != nil {
+		return err
+	}
+	*p = Player{ID: pj.ID, Name: pj.Name, Alive: pj.Alive, kwg: p.kwg}
+	return nil
+}
+
+// MarshalJSON encodes the game, its Settings and all of its players.
+func (g Game) MarshalJSON() ([]byte, error) {
+	var gj = gameJSON{Version: SchemaVersion, ID: g.ID, Channels: g.channels, Players: make([]playerState, 0, len(g.players)), Settings: &g.settings}
+	for _, id := range g.ids() {
+		var p = g.players[id]
+		var ps = playerState{playerJSON: playerJSON{p.ID, p.Name, p.Alive}, KillWord: p.KillWord}
+		if p.target != nil {
+			ps.Target = &p.target.ID
+		}
+		if p.contract != nil {
+			ps.Contract = &p.contract.ID
+		}
+		gj.Players = append(gj.Players, ps)
+	}
+	return json.Marshal(gj)
+}
+
+/*
+UnmarshalJSON decodes a game and its Settings, relinking the chain of players.
+Use SetWordGenerator before playing on with a decoded game.
+*/
+func (g *Game) UnmarshalJSON(b []byte) error {
+	var s = g.settings
+	var gj = gameJSON{Settings: &s}
+	if err := json.Unmarshal(b, &gj); err != nil {
+		return err
+	}
+	if gj.Version != SchemaVersion {
+		return &SchemaVersionError{gj.Version}
+	}
+	var ng = Game{ID: gj.ID, channels: gj.Channels, players: make(map[ID]*Player, len(gj.Players)), kwg: g.kwg, rand: g.rand, settings: s}
+	for _, ps := range gj.Players {
+		ng.players[ps.ID] = &Player{ID: ps.ID, Name: ps.Name, Alive: ps.Alive, kwg: g.kwg, KillWord: ps.KillWord}
+	}
+	var link = func(id *ID) (*Player, error) {
+		if id == nil {
+			return nil, nil
+		}
+		if p, ok := ng.players[*id]; ok {
+			return p, nil
+		}
+		return nil, fmt.Errorf("Unknown player %v in chain", *id)
+	}
+	for _, ps := range gj.Players {
+		var p = ng.players[ps.ID]
+		var err error
+		if p.target, err = link(ps.Target); err != nil {
+			return err
+		}
+		if p.contract, err = link(ps.Contract); err != nil {
+			return err
+		}
+	}
+	*g = ng
+	return nil
+}
@@ -0,0 +1,144 @@
+package assassin
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlayerJSON(t *testing.T) {
+	var (
+		wg   = &WordList{words: []string{"aaaa", "bbbb"}}
+		a, b = NewPlayer(1, "A", wg), NewPlayer(2, "B", wg)
+	)
+	a.SetTarget(b)
+	var j, err = json.Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the KillWord and chain are left out, as secrets
+	if s := string(j); s != `{"id":1,"name":"A","alive":true}` {
+		t.Error("Unexpected encoding", s)
+	}
+	var p Player
+	if err := json.Unmarshal(j, &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != 1 || p.Name != "A" || !p.Alive || p.KillWord != "" || p.target != nil {
+		t.Error("Unexpected decoding", p)
+	}
+	t.Run("Event", func(t *testing.T) {
+		var j, err = json.Marshal(TargetAssigned{Player: *a, Target: *b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := string(j); strings.Contains(s, "aaaa") || strings.Contains(s, "target\":") {
+			t.Error("Secrets in event encoding", s)
+		}
+	})
+}
+
+func TestGameJSON(t *testing.T) {
+	var (
+		pl = map[ID]string{1: "A", 2: "B", 3: "C"}
+		g  = NewGame(1, pl, NewWordList([]string{"aaaa", "bbbb", "cccc", "dddd"}, nil), nil)
+	)
+	g.SetChannels("#game")
+	g.Start()
+	g.players[2].SetEliminated()
+	var j, err = json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d = new(Game)
+	if err := json.Unmarshal(j, d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != g.ID || d.Status() != 2 || d.Channels()[0] != "#game" {
+		t.Error("Unexpected decoding", d)
+	}
+	for id, p := range g.players {
+		var dp = d.players[id]
+		if dp.Name != p.Name || dp.Alive != p.Alive || dp.KillWord != p.KillWord {
+			t.Error("Player", dp, "!=", p)
+		}
+		if p.target != nil && (dp.target == nil || dp.target != d.players[p.target.ID]) {
+			t.Error("Player", dp, "target not restored")
+		}
+		if p.contract != nil && (dp.contract == nil || dp.contract != d.players[p.contract.ID]) {
+			t.Error("Player", dp, "contract not restored")
+		}
+	}
+	t.Run("Version", func(t *testing.T) {
+		var err = json.Unmarshal([]byte(`{"version":99,"id":1,"players":[]}`), new(Game))
+		if ve, ok := err.(*SchemaVersionError); !ok || ve.Version != 99 {
+			t.Error("Expected SchemaVersionError, got", err)
+		}
+	})
+	t.Run("Chain", func(t *testing.T) {
+		var err = json.Unmarshal([]byte(`{"version":1,"id":1,"players":[{"id":1,"name":"A","alive":true,"target":5}]}`), new(Game))
+		if err == nil {
+			t.Error("Expected error for unknown player in chain")
+		}
+	})
+}
+
+func TestSettingsJSON(t *testing.T) {
+	var (
+		pl = map[ID]string{1: "A", 2: "B", 3: "C"}
+		ac = &AntiCheat{MaxWords: 20, MaxAttacks: 2, Window: time.Minute, Penalty: WarnPenalty}
+		g  = NewGame(1, pl, NewWordList([]string{"aaaa", "bbbb", "cccc"}, nil), nil)
+	)
+	g.SetMatcher(WordMatcher{Stem: true, Leet: true})
+	g.SetAntiCheat(ac)
+	g.SetIntel(10 * time.Minute)
+	g.SetTargetAssigner(Teams{1: "red", 2: "blue"})
+	var j, err = json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d = new(Game)
+	if err := json.Unmarshal(j, d); err != nil {
+		t.Fatal(err)
+	}
+	var s = d.settings
+	if m, ok := s.Matcher.(WordMatcher); !ok || !m.Stem || !m.Leet {
+		t.Error("Unexpected matcher", s.Matcher)
+	}
+	if s.AntiCheat == nil || fmt.Sprint(*s.AntiCheat) != fmt.Sprint(*ac) {
+		t.Error("Unexpected anti-cheat", s.AntiCheat)
+	}
+	if s.Intel != 10*time.Minute {
+		t.Error("Unexpected intel", s.Intel)
+	}
+	if ts, ok := s.Assigner.(Teams); !ok || ts[1] != "red" || ts[2] != "blue" || len(ts) != 2 {
+		t.Error("Unexpected assigner", s.Assigner)
+	}
+	t.Run("Exact", func(t *testing.T) {
+		g.SetMatcher(ExactMatcher)
+		var j, _ = json.Marshal(g)
+		var d = new(Game)
+		if err := json.Unmarshal(j, d); err != nil {
+			t.Fatal(err)
+		}
+		if d.settings.Matcher != ExactMatcher {
+			t.Error("Unexpected matcher", d.settings.Matcher)
+		}
+	})
+	t.Run("Custom", func(t *testing.T) {
+		// Nb. a Matcher the encoding leaves out is kept from the game decoded into.
+		var never = MatcherFunc(func(text, word string) bool { return false })
+		g.SetMatcher(never)
+		var j, _ = json.Marshal(g)
+		var d = new(Game)
+		d.SetMatcher(never)
+		if err := json.Unmarshal(j, d); err != nil {
+			t.Fatal(err)
+		}
+		if d.says("aaaa", "aaaa") || d.settings.Intel != 10*time.Minute {
+			t.Error("Unexpected settings", d.settings)
+		}
+	})
+}
@@ -10,15 +10,28 @@ Rules are as follows:
 	 3. A player can counter an attack on them by saying their own KillWord within the window of attack. In this case, after the window expires, the attacker is killed instead.
 
 Game Setup:
-	Create a new GameEngine to run the game.
+	Create a new GameEngine to run games. A single engine can host many games at once.
 	Create a new Game instance by calling NewGame, passing in player details.
-	Call GameEngine.Run(Game) in a sub-routine to run the game.
+	Tests can pass NewGameEngine a fake Clock, and NewGame and NewWordList a seeded Rand, to play games deterministically (see package assassintest).
+	Call GameEngine.Run(Game) in a sub-routine to run the game, or GameEngine.RunContext to be able to cancel it.
 	Player messages controlling game flow can be input through GameEngine.IncomingTalk.
+	Alternatively, let players sign up and start games from chat by passing messages to Commands.Handle.
+	End a game early with GameEngine.Stop.
+	Use Game.SetChannels to restrict in-game speech to the game's own channels.
+	Use Game.SetMatcher to change how KillWords are recognised in talk; by default they must be said as whole words.
+	Use Game.SetAntiCheat to flag players fishing for KillWords with word dumps or random attacks.
+	Use Game.SetIntel to keep long games moving, with hints for players about who is hunting them.
+	Use Game.SetTargetAssigner with Teams for team games, where players only target rival teams and the last team standing wins.
+	Game events are published to Subscribers added with GameEngine.Subscribe.
+	Game inputs can be recorded with GameEngine.SetLog, and played back by a Replayer.
+	Running games can be saved to a Store with GameEngine.SetStore, and picked up after a restart with GameEngine.Resume or ResumeContext.
+	Both keep the game's Settings, so resumed and replayed games play by the rules they started with.
 */
 package assassin
 
 import (
-	"strings"
+	"context"
+	"sync"
 	"time"
 )
 
@@ -32,44 +45,25 @@ const (
 	AttackAction
 )
 
-type attackQueue struct {
-	q []struct {
-		p, t ID
-		r    bool
-	}
-}
-
-func newAttackQueue() *attackQueue {
-	var a = new(attackQueue)
-	a.q = make([]struct {
-		p, t ID
-		r    bool
-	}, 0, 1)
-	return a
-}
-
-func (a *attackQueue) each(f func(p, t ID, r bool) bool) {
-	for i, v := range a.q {
-		a.q[i].r = f(v.p, v.t, v.r)
-	}
-}
-
-func (a *attackQueue) push(p, t ID) {
-	a.q = append(a.q, struct {
-		p, t ID
-		r    bool
-	}{p, t, false})
-}
-
-func (a *attackQueue) pop() (p, t ID, r bool) {
-	// Nb. Will panic if nothing to pop.
-	var i = a.q[0]
-	a.q = a.q[1:]
-	return i.p, i.t, i.r
+/*
+Talk is the envelope for a chat message sent to the engine.
+A zero Game routes the talk by Channel, to every running game listing that channel.
+Name is the speaker's display name, used when signing up through Commands.
+Account, if known, is the speaker's account on their network: unlike Name, it is authenticated and cannot be taken by anyone else.
+*/
+type Talk struct {
+	Game    ID
+	Channel string
+	Speaker ID
+	Name    string
+	Account string
+	Time    time.Time
+	Text    string
 }
 
 /*
-MessageHandler interface for the GameEngine to report events to.
+MessageHandler interface for sending rendered game messages to players.
+See MessageRenderer.
 	Announce sends a public message to all players in the game.
 	Notify sends a private message to an individual player.
 */
@@ -80,163 +74,352 @@ type MessageHandler interface {
 
 /*
 AttackTimingFunc interface controls delay between a player attack and its execution (and therefore how long the target has to counter the attack).
+The package provides FixedTiming, UniformTiming, NormalTiming, ExponentialTiming and ScaledTiming, or see TimingConfig.
 */
 type AttackTimingFunc interface {
 	Calc() time.Duration
 }
 
-// GameEngine contains state information for running a game.
+/*
+AliveTimingFunc is an AttackTimingFunc whose delay depends on how many players are still alive (see Game.Status).
+The engine calls CalcAlive in place of Calc.
+*/
+type AliveTimingFunc interface {
+	AttackTimingFunc
+	CalcAlive(alive int) time.Duration
+}
+
+// GameEngine contains state information for running games.
 type GameEngine struct {
-	tpl     Lang
-	msg     MessageHandler
-	atf     AttackTimingFunc
-	running bool
-	talk    chan struct {
-		ID
-		string
-	}
-	action chan GameActionConst
+	tpl   Lang
+	atf   AttackTimingFunc
+	clock Clock
+	mu    sync.Mutex
+	games map[ID]*gameRun
+	subs  []Subscriber
+	log   GameLog
+	store Store
+	next  ID
 }
 
-// NewGameEngine returns a new GameEngine instance.
-func NewGameEngine(tpl Lang, msg MessageHandler, atf AttackTimingFunc) *GameEngine {
+/*
+NewGameEngine returns a new GameEngine instance.
+If msg is not nil, it is subscribed to all game events through a MessageRenderer using tpl.
+Games are timed by clock, or the system clock if nil.
+*/
+func NewGameEngine(tpl Lang, msg MessageHandler, atf AttackTimingFunc, clock Clock) *GameEngine {
 	var e = new(GameEngine)
 	e.tpl = tpl
-	e.msg = msg
 	e.atf = atf
-	e.running = false
-	e.talk = make(chan struct {
-		ID
-		string
-	})
-	e.action = make(chan GameActionConst)
+	if e.clock = clock; clock == nil {
+		e.clock = systemClock{}
+	}
+	e.games = make(map[ID]*gameRun)
+	if msg != nil {
+		e.Subscribe(NewMessageRenderer(tpl, msg))
+	}
 	return e
 }
 
-func (e *GameEngine) notifyStatus(p Player) {
-	var s string
-	if p.Alive {
-		if t, ok := p.GetTarget(); ok {
-			s = e.tpl.Fmt(e.tpl.PT, t.Name, p.KillWord)
-		} else {
-			s = e.tpl.PA
+// Subscribe s to the events of all games run on the engine.
+func (e *GameEngine) Subscribe(s Subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subs = append(e.subs, s)
+}
+
+// Unsubscribe s from engine events.
+func (e *GameEngine) Unsubscribe(s Subscriber) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for i, v := range e.subs {
+		if v == s {
+			e.subs = append(e.subs[:i:i], e.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetLog sets the GameLog that the engine records game inputs to. Use nil to stop recording.
+func (e *GameEngine) SetLog(l GameLog) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.log = l
+}
+
+// SetStore sets the Store that the engine saves running games to after each change. Use nil to stop saving.
+func (e *GameEngine) SetStore(s Store) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.store = s
+}
+
+// publish ev to all subscribers, in order of subscription.
+func (e *GameEngine) publish(ev Event) {
+	e.mu.Lock()
+	var subs = e.subs
+	e.mu.Unlock()
+	for _, s := range subs {
+		s.Handle(ev)
+	}
+}
+
+/*
+NewID returns a game ID not yet given out by the engine, nor used by any game running on it or saved in its Store.
+Use it to start games from many sources, such as several Commands, on one engine without their IDs clashing.
+*/
+func (e *GameEngine) NewID() ID {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var stored = make(map[ID]bool)
+	if e.store != nil {
+		// Nb. a failure to list the store only risks a clash with a game not resumed yet.
+		var ids, _ = e.store.List()
+		for _, id := range ids {
+			stored[id] = true
+		}
+	}
+	for {
+		e.next++
+		if _, ok := e.games[e.next]; !ok && !stored[e.next] {
+			return e.next
+		}
+	}
+}
+
+// register adds r to the games running on the engine.
+func (e *GameEngine) register(r *gameRun) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.games[r.g.ID]; ok {
+		return &GameInProgressError{e.tpl}
+	}
+	e.attach(r)
+	e.games[r.g.ID] = r
+	return nil
+}
+
+// attach the engine's hooks to r.
+func (e *GameEngine) attach(r *gameRun) {
+	var g = r.g
+	r.now = e.clock.Now
+	r.publish = e.publish
+	r.schedule = func(n int) {
+		var alive = r.alive
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			var d time.Duration
+			if atf, ok := e.atf.(AliveTimingFunc); ok {
+				d = atf.CalcAlive(alive)
+			} else {
+				d = e.atf.Calc()
+			}
+			select {
+			case r.due <- attackDue{n, e.clock.Now().Add(d)}:
+			case <-r.done:
+			}
+		}()
+	}
+	if s := e.store; s != nil {
+		// Nb. a failure to save does not stop the game.
+		r.save = func() { s.Save(r.snapshot()) }
+	}
+	if l := e.log; l != nil {
+		// Nb. a failure to record does not stop the game.
+		r.record = func(le LogEntry) { l.Append(le) }
+		g.SetWordGenerator(&recordingWords{g.kwg, func(w string) {
+			var le = r.entry(LogWord)
+			le.Word = w
+			l.Append(le)
+		}})
+	}
+}
+
+// wait until at to carry out attack number n. The wait is cancelled if the game ends first.
+func (e *GameEngine) wait(r *gameRun, n int, at time.Time) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		select {
+		case <-e.clock.After(at.Sub(e.clock.Now())):
+		case <-r.done:
+			return
 		}
-	} else {
-		s = e.tpl.PD
+		select {
+		case r.action <- gameAction{AttackAction, n, ""}:
+		case <-r.done:
+		}
+	}()
+}
+
+// unregister removes a finished game from the engine and releases anything waiting on it.
+func (e *GameEngine) unregister(r *gameRun) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.games, r.g.ID)
+	close(r.done)
+	if e.store != nil {
+		e.store.Delete(r.g.ID)
 	}
-	e.msg.Notify(p, s)
 }
 
-// GameInProgressError is returned when a game is already running.
+// GameInProgressError is returned when a game with the same ID is already running.
 type GameInProgressError struct {
 	tpl Lang
 }
 
 func (e GameInProgressError) Error() string { return e.tpl.EIP }
 
-// Run a Game on the engine. Only one game can be run per engine at once.
-func (e *GameEngine) Run(g *Game) error {
-	if e.running {
-		return &GameInProgressError{e.tpl}
+// GameNotRunningError is returned when stopping a game that is not running.
+type GameNotRunningError struct {
+	tpl Lang
+}
+
+func (e GameNotRunningError) Error() string { return e.tpl.ENR }
+
+/*
+Run a Game on the engine. Many games can run at once, but only one per Game.ID.
+Returns the result of the game once it is over.
+*/
+func (e *GameEngine) Run(g *Game) (*GameResult, error) {
+	return e.RunContext(context.Background(), g)
+}
+
+/*
+RunContext runs a Game on the engine until it is over, it is stopped, or ctx is done.
+Returns the result of the game once all its pending attacks have been cancelled.
+*/
+func (e *GameEngine) RunContext(ctx context.Context, g *Game) (*GameResult, error) {
+	var r = newGameRun(g)
+	if err := e.register(r); err != nil {
+		return nil, err
+	}
+	r.begin(g.startingOrder())
+	r.save()
+	return e.loop(ctx, r), nil
+}
+
+/*
+Resume a game saved in Snapshot s, using kwg to hand out future KillWords.
+Pending attacks are carried out once their remaining countdown expires.
+Returns the result of the game once it is over.
+*/
+func (e *GameEngine) Resume(s *Snapshot, kwg WordGenerator) (*GameResult, error) {
+	return e.ResumeContext(context.Background(), s, kwg)
+}
+
+// ResumeContext resumes a game saved in Snapshot s, like Resume, until it is over, it is stopped, or ctx is done.
+func (e *GameEngine) ResumeContext(ctx context.Context, s *Snapshot, kwg WordGenerator) (*GameResult, error) {
+	var r = restoreGameRun(s, kwg)
+	if err := e.register(r); err != nil {
+		return nil, err
 	}
-	e.running = true
-	e.msg.Announce(e.tpl.GS)
-	g.Start()
-	g.WithPlayers(func(p Player) {
-		e.notifyStatus(p)
-	})
-	// the main event loop
-	var pc = g.Status()
-	var attacks = newAttackQueue()
-	var elimination = func(p Player) {
-		e.msg.Announce(e.tpl.Fmt(e.tpl.GD, p.Name))
-		if c, ok := p.GetContract(); ok {
-			e.notifyStatus(c)
+	for _, a := range r.attacks.q {
+		if a.due.IsZero() {
+			r.schedule(a.n)
+		} else {
+			e.wait(r, a.n, a.due)
 		}
-		pc--
 	}
-	for pc > 1 {
+	return e.loop(ctx, r), nil
+}
+
+/*
+Stop the running game with given id, announcing reason to the players.
+An empty reason is replaced by a default from Lang.
+*/
+func (e *GameEngine) Stop(id ID, reason string) error {
+	e.mu.Lock()
+	var r, ok = e.games[id]
+	e.mu.Unlock()
+	if !ok {
+		return &GameNotRunningError{e.tpl}
+	}
+	if reason == "" {
+		reason = e.tpl.GQR
+	}
+	select {
+	case r.action <- gameAction{QuitAction, 0, reason}:
+		return nil
+	case <-r.done:
+		return &GameNotRunningError{e.tpl}
+	}
+}
+
+/*
+loop is the main event loop for a game run, returning once the game is over.
+The run is then unregistered, and loop waits for its timers to finish.
+*/
+func (e *GameEngine) loop(ctx context.Context, r *gameRun) *GameResult {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
 		select {
-		case chat := <-e.talk:
-			var p, ok = g.GetPlayer(chat.ID)
-			if ok && p.Alive {
-				/*
-					When analysing the chatter, check for an assassination first.
-					If a message includes both player's KillWord and their contract's, the assassination will take precedence over the attack/counter.
-				*/
-				if c, ok := p.GetContract(); ok && strings.Contains(chat.string, c.KillWord) {
-					// p assassinated
-					if k, ok := g.ResolvePlayerKill(p.ID); ok {
-						elimination(k)
-					}
-				} else if strings.Contains(chat.string, p.KillWord) {
-					var retaliated = false
-					attacks.each(func(ap, at ID, r bool) bool {
-						if at == p.ID && !r {
-							// p is retaliating
-							retaliated = true
-							return true
-						}
-						return false
-
-					})
-					if !retaliated {
-						// p is attacking
-						if t, ok := p.GetTarget(); ok {
-							attacks.push(p.ID, t.ID)
-							go func() {
-								time.Sleep(e.atf.Calc())
-								e.action <- AttackAction
-							}()
-						}
-					}
-				}
+		case <-ctx.Done():
+			select {
+			case r.action <- gameAction{QuitAction, 0, e.tpl.GQR}:
+			case <-r.done:
 			}
-		case a := <-e.action:
-			switch a {
+		case <-r.done:
+		}
+	}()
+	var (
+		quiet <-chan time.Time
+		kills = -1
+	)
+	for !r.over() {
+		if d := r.g.settings.Intel; d > 0 && len(r.kills) != kills {
+			// Nb. each elimination restarts the wait for intel.
+			kills = len(r.kills)
+			quiet = e.clock.After(d)
+		}
+		select {
+		case <-quiet:
+			r.handleIntel()
+			quiet = e.clock.After(r.g.settings.Intel)
+		case chat := <-r.talk:
+			r.handleTalk(chat)
+		case d := <-r.due:
+			r.attacks.setDue(d.n, d.at)
+			r.changed = true
+			e.wait(r, d.n, d.at)
+		case a := <-r.action:
+			switch a.GameActionConst {
 			case QuitAction:
-				pc = 0
+				r.quit(a.reason)
 			case AttackAction:
-				var pid, tid, r = attacks.pop()
-				if r {
-					if k, ok := g.ResolvePlayerCounter(tid, pid); ok {
-						if t, ok := g.GetPlayer(tid); ok {
-							e.msg.Notify(t, e.tpl.PCS)
-						}
-						elimination(k)
-					}
-				} else {
-					if k, ok := g.ResolvePlayerAttack(pid, tid); ok {
-						if p, ok := g.GetPlayer(pid); ok {
-							e.msg.Notify(p, e.tpl.PAS)
-						}
-						elimination(k)
-					}
-				}
+				r.handleAttack(a.n)
 			}
 		}
-	}
-	e.msg.Announce(e.tpl.GE)
-	var w = make([]string, 0, 1)
-	g.WithPlayers(func(p Player) {
-		if p.Alive {
-			w = append(w, p.Name)
+		if r.changed {
+			// Nb. talk that changes nothing, like most chatter, is not saved.
+			r.save()
+			r.changed = false
 		}
-	})
-	if len(w) == 1 {
-		e.msg.Announce(e.tpl.Fmt(e.tpl.GW, w[0]))
-	} else {
-		e.msg.Announce(e.tpl.Fmt(e.tpl.GWM, w))
 	}
-	e.running = false
-	return nil
+	var res = r.end()
+	e.unregister(r)
+	r.wg.Wait()
+	return res
 }
 
-// IncomingTalk is used to send incoming chatter from players to the running game.
+// IncomingTalk is used to send incoming chatter from players to the running games.
+// Talk is routed by its game ID and channel; see Talk and Game.SetChannels.
 // This talk is responsible for triggering actions during the game.
-func (e *GameEngine) IncomingTalk(from ID, text string) {
-	e.talk <- struct {
-		ID
-		string
-	}{from, text}
+func (e *GameEngine) IncomingTalk(t Talk) {
+	e.mu.Lock()
+	var rs = make([]*gameRun, 0, 1)
+	for _, r := range e.games {
+		if r.accepts(t) {
+			rs = append(rs, r)
+		}
+	}
+	e.mu.Unlock()
+	for _, r := range rs {
+		select {
+		case r.talk <- t:
+		case <-r.done:
+		}
+	}
 }
@@ -1,51 +1,15 @@
 package assassin
 
 import (
+	"context"
 	"errors"
+	"reflect"
 	"regexp"
 	"testing"
 	"time"
-)
 
-func TestAttackQueue(t *testing.T) {
-	var q = newAttackQueue()
-	t.Run("push", func(t *testing.T) {
-		q.push(1, 2)
-		q.push(3, 4)
-		if len(q.q) != 2 {
-			t.Error("Push to q failed")
-		}
-	})
-	t.Run("each", func(tt *testing.T) {
-		var c = 0
-		q.each(func(p, t ID, r bool) bool {
-			c++
-			if r {
-				tt.Error("Expected r = false for", p, t)
-			}
-			return p == 1
-		})
-		if c != 2 {
-			tt.Error("Iterated", c, "times, expected 2")
-		}
-		q.each(func(p, t ID, r bool) bool {
-			if p == 1 && !r || p != 1 && r {
-				tt.Error("Expected r =", !r, "for", p, t)
-			}
-			return r
-		})
-	})
-	t.Run("pop", func(tt *testing.T) {
-		var p, t, r = q.pop()
-		if p != 1 || t != 2 || !r {
-			tt.Error("Expected 1, 2, true, got", p, t, r)
-		}
-		var p2, t2, r2 = q.pop()
-		if p2 != 3 || t2 != 4 || r2 {
-			tt.Error("Expected 3, 4, false, got", p2, t2, r2)
-		}
-	})
-}
+	"github.com/joshringer/assassinbot/assassin/assassintest"
+)
 
 func timeout(d time.Duration) chan time.Duration {
 	var c = make(chan time.Duration)
@@ -167,38 +131,17 @@ func (h *testMessageHandler) Notify(p Player, s string) {
 	}
 }
 
-type triggeredTimingFunc struct {
-	t    *testing.T
-	wait chan time.Duration
-}
-
-func newTriggeredTimingFunc(t *testing.T) *triggeredTimingFunc {
-	return &triggeredTimingFunc{t, make(chan time.Duration)}
-}
-
-func (ttf *triggeredTimingFunc) set(t *testing.T) {
-	ttf.t = t
-}
-
-func (ttf *triggeredTimingFunc) Calc() time.Duration {
-	var to = timeout(time.Second)
-	select {
-	case d := <-ttf.wait:
-		return d
-	case <-to:
-		ttf.t.Error("Timeout waiting for trigger")
-		return 0
-	}
-
+// testClock returns a fake clock for engine tests, stopped at noon on New Year's Day 2020.
+func testClock() *assassintest.Clock {
+	return assassintest.NewClock(time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC))
 }
 
 func TestGameEngineBasic(t *testing.T) {
 	var mh = newTestMessageHandler(t)
-	var tf = newTriggeredTimingFunc(t)
-	var e = NewGameEngine(LangEn, mh, tf)
-	var g = NewGame(1, map[ID]string{1: "A"}, NewWordList([]string{"aaaa"}))
+	var e = NewGameEngine(LangEn, mh, FixedTiming(time.Minute), testClock())
+	var g = NewGame(1, map[ID]string{1: "A"}, NewWordList([]string{"aaaa"}, nil), nil)
 	var res = make(chan error)
-	go func() { res <- e.Run(g) }()
+	go func() { var _, err = e.Run(g); res <- err }()
 	go func() {
 		time.Sleep(10 * time.Second)
 		res <- errors.New("Game run timeout")
@@ -216,19 +159,20 @@ func TestGameEngineBasic(t *testing.T) {
 	}
 }
 
-func input(t *testing.T, e *GameEngine, p *Player, s string) {
+func input(t *testing.T, e *GameEngine, g *Game, p *Player, s string) {
 	t.Logf("@%v << %v", p.Name, s)
-	e.IncomingTalk(p.ID, s)
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: p.ID, Time: time.Now(), Text: s})
 }
 
 func TestGameEngineRunthrough(t *testing.T) {
 	var mh = newTestMessageHandler(t)
-	var tf = newTriggeredTimingFunc(t)
-	var e = NewGameEngine(LangEn, mh, tf)
+	var clock = testClock()
+	var e = NewGameEngine(LangEn, mh, FixedTiming(time.Minute), clock)
 	var g = NewGame(
 		1,
 		map[ID]string{1: "Ace", 2: "Bee", 3: "Cee", 4: "Dee"},
-		NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5", "kw6", "kw7"}),
+		NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5", "kw6", "kw7"}, nil),
+		nil,
 	)
 	var s *Player
 	var rpt = regexp.MustCompile(LangEn.Fmt(LangEn.PT, ".+", ".+"))
@@ -238,7 +182,12 @@ func TestGameEngineRunthrough(t *testing.T) {
 		s = p
 	}
 	var res = make(chan error)
-	go func() { res <- e.Run(g) }()
+	var result *GameResult
+	go func() {
+		var r, err = e.Run(g)
+		result = r
+		res <- err
+	}()
 	go func() {
 		time.Sleep(10 * time.Second)
 		res <- errors.New("Game run timeout")
@@ -247,25 +196,19 @@ func TestGameEngineRunthrough(t *testing.T) {
 	mh.expect(sm...)
 	t.Run("attack", func(t *testing.T) {
 		mh.set(t)
-		tf.set(t)
 		var t1 = s.target
 		if t1 == nil {
 			t.Fatal("Player", s, "missing target")
 		}
-		input(t, e, s, "Text including "+s.KillWord)
-		var to = timeout(time.Second)
-		select {
-		case tf.wait <- time.Nanosecond:
-		case d := <-to:
-			t.Error("Wait not requested within", d)
-		}
+		input(t, e, g, s, "Text including "+s.KillWord)
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
 		mh.expect(playerString{*s, LangEn.PAS})
 		mh.expect(LangEn.Fmt(LangEn.GD, t1.Name))
 		mh.expect(playerRegexp{*s, rpt})
 	})
 	t.Run("counter", func(t *testing.T) {
 		mh.set(t)
-		tf.set(t)
 		var t1 = s.target
 		if t1 == nil {
 			t.Fatal("Player", s, "missing target")
@@ -274,14 +217,10 @@ func TestGameEngineRunthrough(t *testing.T) {
 		if t2 == nil {
 			t.Fatal("Player", t1, "missing target")
 		}
-		input(t, e, t1, "Text including "+t1.KillWord)
-		input(t, e, t2, "Response including "+t2.KillWord)
-		var to = timeout(time.Second)
-		select {
-		case tf.wait <- time.Nanosecond:
-		case d := <-to:
-			t.Error("Wait not requested within", d)
-		}
+		input(t, e, g, t1, "Text including "+t1.KillWord)
+		input(t, e, g, t2, "Response including "+t2.KillWord)
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
 		mh.expect(playerString{*t2, LangEn.PCS})
 		mh.expect(LangEn.Fmt(LangEn.GD, t1.Name))
 		mh.expect(playerRegexp{*s, rpt})
@@ -292,7 +231,7 @@ func TestGameEngineRunthrough(t *testing.T) {
 		if t1 == nil {
 			t.Fatal("Player", s, "missing target")
 		}
-		input(t, e, t1, "Text including "+s.KillWord)
+		input(t, e, g, t1, "Text including "+s.KillWord)
 		mh.expect(LangEn.Fmt(LangEn.GD, t1.Name))
 		mh.expect(playerRegexp{*s, rpt})
 	})
@@ -302,4 +241,210 @@ func TestGameEngineRunthrough(t *testing.T) {
 	if r != nil {
 		t.Fatal(r)
 	}
+	if len(result.Winners) != 1 || result.Winners[0].ID != s.ID {
+		t.Error("Unexpected winners", result.Winners)
+	}
+	var methods = make([]KillMethod, 0)
+	for _, k := range result.Kills {
+		methods = append(methods, k.Method)
+	}
+	if len(methods) != 3 || methods[0] != AttackMethod || methods[1] != CounterMethod || methods[2] != AssassinationMethod {
+		t.Error("Unexpected kill methods", methods)
+	}
+	if el := result.Eliminated(); len(el) != 3 || el[2].Alive {
+		t.Error("Unexpected elimination order", el)
+	}
+	if result.Stopped || result.Duration != result.Ended.Sub(result.Started) || result.Duration != 2*time.Minute {
+		t.Error("Unexpected result", result)
+	}
+}
+
+// eventChan passes on game events.
+type eventChan chan Event
+
+func (c eventChan) Handle(ev Event) { c <- ev }
+
+func TestGameEngineClock(t *testing.T) {
+	var clock = testClock()
+	var start = clock.Now()
+	var ec = make(eventChan, 32)
+	var e = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), clock)
+	e.Subscribe(ec)
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5"}, nil), nil)
+	g.SetStartOrder([]ID{1, 2, 3})
+	var res = make(chan *GameResult)
+	go func() { var r, _ = e.Run(g); res <- r }()
+	var words = make(map[ID]string)
+	// await reads events up to the next one of the same type as want
+	var await = func(want Event) Event {
+		for {
+			select {
+			case ev := <-ec:
+				if ta, ok := ev.(TargetAssigned); ok {
+					words[ta.Player.ID] = ta.Player.KillWord
+				}
+				if reflect.TypeOf(ev) == reflect.TypeOf(want) {
+					return ev
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("No %T event", want)
+			}
+		}
+	}
+	var say = func(id ID, s string) {
+		e.IncomingTalk(Talk{Game: 1, Speaker: id, Time: clock.Now(), Text: s})
+	}
+	if ev := await(GameStarted{}); ev.At() != start {
+		t.Error("Unexpected start", ev.At())
+	}
+	for i := 0; i < 3; i++ {
+		await(TargetAssigned{})
+	}
+	say(1, "I attack with "+words[1])
+	clock.BlockUntil(1)
+	if clock.Advance(time.Minute - time.Nanosecond); clock.Waiters() != 1 {
+		t.Error("Attack carried out early")
+	}
+	clock.Advance(time.Nanosecond)
+	if ev := await(PlayerEliminated{}).(PlayerEliminated); ev.Player.ID != 2 || ev.Method != AttackMethod || ev.At() != start.Add(time.Minute) {
+		t.Error("Unexpected elimination", ev)
+	}
+	await(TargetAssigned{})
+	say(1, "I attack with "+words[1])
+	say(3, "I counter with "+words[3])
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	if ev := await(PlayerEliminated{}).(PlayerEliminated); ev.Player.ID != 1 || ev.Method != CounterMethod {
+		t.Error("Unexpected elimination", ev)
+	}
+	var r = <-res
+	if len(r.Winners) != 1 || r.Winners[0].ID != 3 || r.Duration != 2*time.Minute {
+		t.Error("Unexpected result", r)
+	}
+}
+
+func TestGameEngineConcurrent(t *testing.T) {
+	var mh = newTestMessageHandler(t)
+	var e = NewGameEngine(LangEn, mh, FixedTiming(time.Minute), testClock())
+	var g1 = NewGame(1, map[ID]string{1: "Ace", 2: "Bee"}, NewWordList([]string{"kw1", "kw2"}, nil), nil)
+	var g2 = NewGame(2, map[ID]string{3: "Cee", 4: "Dee"}, NewWordList([]string{"kw3", "kw4"}, nil), nil)
+	var rpt = regexp.MustCompile(LangEn.Fmt(LangEn.PT, ".+", ".+"))
+	var res1, res2 = make(chan error), make(chan error)
+	go func() { var _, err = e.Run(g1); res1 <- err }()
+	mh.expect(LangEn.GS, playerRegexp{Player{ID: 1}, rpt}, playerRegexp{Player{ID: 2}, rpt})
+	go func() { var _, err = e.Run(g2); res2 <- err }()
+	mh.expect(LangEn.GS, playerRegexp{Player{ID: 3}, rpt}, playerRegexp{Player{ID: 4}, rpt})
+	t.Run("duplicate", func(t *testing.T) {
+		var dup = NewGame(1, map[ID]string{5: "Eee"}, NewWordList([]string{"kw5"}, nil), nil)
+		if _, err := e.Run(dup); err == nil {
+			t.Error("Expected error running duplicate game ID")
+		} else if _, ok := err.(*GameInProgressError); !ok {
+			t.Error("Unexpected error", err)
+		}
+	})
+	t.Run("isolated", func(t *testing.T) {
+		mh.set(t)
+		var c, d = g2.players[3], g2.players[4]
+		input(t, e, g2, c, "Text including "+d.KillWord)
+		mh.expect(LangEn.Fmt(LangEn.GD, c.Name))
+		mh.expect(playerRegexp{*d, rpt})
+		mh.expect(LangEn.GE, LangEn.Fmt(LangEn.GW, d.Name))
+		if r := <-res2; r != nil {
+			t.Error(r)
+		}
+		if g1.Status() != 2 {
+			t.Error("Game", g1.ID, "affected by talk in game", g2.ID)
+		}
+	})
+	mh.set(t)
+	var a, b = g1.players[1], g1.players[2]
+	input(t, e, g1, b, "Text including "+a.KillWord)
+	mh.expect(LangEn.Fmt(LangEn.GD, b.Name))
+	mh.expect(playerRegexp{*a, rpt})
+	mh.expect(LangEn.GE, LangEn.Fmt(LangEn.GW, a.Name))
+	if r := <-res1; r != nil {
+		t.Fatal(r)
+	}
+}
+
+func TestGameEngineChannels(t *testing.T) {
+	var mh = newTestMessageHandler(t)
+	var e = NewGameEngine(LangEn, mh, FixedTiming(time.Minute), testClock())
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee"}, NewWordList([]string{"kw1", "kw2"}, nil), nil)
+	g.SetChannels("#game")
+	var rpt = regexp.MustCompile(LangEn.Fmt(LangEn.PT, ".+", ".+"))
+	var res = make(chan error)
+	go func() { var _, err = e.Run(g); res <- err }()
+	mh.expect(LangEn.GS, playerRegexp{Player{ID: 1}, rpt}, playerRegexp{Player{ID: 2}, rpt})
+	var a, b = g.players[1], g.players[2]
+	// talk outside the game's channels is not in-game speech
+	e.IncomingTalk(Talk{Game: g.ID, Channel: "#other", Speaker: a.ID, Text: b.KillWord})
+	e.IncomingTalk(Talk{Channel: "#other", Speaker: a.ID, Text: b.KillWord})
+	// talk without a game ID is routed by channel
+	e.IncomingTalk(Talk{Channel: "#game", Speaker: b.ID, Text: a.KillWord})
+	mh.expect(LangEn.Fmt(LangEn.GD, b.Name))
+	mh.expect(playerRegexp{*a, rpt})
+	mh.expect(LangEn.GE, LangEn.Fmt(LangEn.GW, a.Name))
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+}
+
+func TestGameEngineStop(t *testing.T) {
+	var mh = newTestMessageHandler(t)
+	var clock = testClock()
+	var e = NewGameEngine(LangEn, mh, FixedTiming(time.Minute), clock)
+	var rpt = regexp.MustCompile(LangEn.Fmt(LangEn.PT, ".+", ".+"))
+	var rwm = regexp.MustCompile(LangEn.Fmt(LangEn.GWM, ".+"))
+	var newGame = func() *Game {
+		return NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3"}, nil), nil)
+	}
+	var started = func() []interface{} {
+		return []interface{}{LangEn.GS, playerRegexp{Player{ID: 1}, rpt}, playerRegexp{Player{ID: 2}, rpt}, playerRegexp{Player{ID: 3}, rpt}}
+	}
+	t.Run("Stop", func(t *testing.T) {
+		mh.set(t)
+		var g = newGame()
+		var res = make(chan *GameResult)
+		go func() {
+			var r, err = e.RunContext(context.Background(), g)
+			if err != nil {
+				t.Error(err)
+			}
+			res <- r
+		}()
+		mh.expect(started()...)
+		// leave an attack pending, to be cancelled
+		var a = g.players[1]
+		input(t, e, g, a, a.KillWord)
+		clock.BlockUntil(1)
+		if err := e.Stop(g.ID, "bored"); err != nil {
+			t.Error(err)
+		}
+		mh.expect(LangEn.Fmt(LangEn.GQ, "bored"), rwm)
+		var r = <-res
+		if !r.Stopped || r.Reason != "bored" || len(r.Winners) != 3 {
+			t.Error("Unexpected result", r)
+		}
+		if err := e.Stop(g.ID, ""); err == nil {
+			t.Error("Expected error stopping finished game")
+		} else if _, ok := err.(*GameNotRunningError); !ok {
+			t.Error("Unexpected error", err)
+		}
+	})
+	t.Run("Context", func(t *testing.T) {
+		mh.set(t)
+		var ctx, cancel = context.WithCancel(context.Background())
+		var res = make(chan *GameResult)
+		go func() {
+			var r, _ = e.RunContext(ctx, newGame())
+			res <- r
+		}()
+		mh.expect(started()...)
+		cancel()
+		mh.expect(LangEn.Fmt(LangEn.GQ, LangEn.GQR), rwm)
+		if r := <-res; !r.Stopped || r.Reason != LangEn.GQR {
+			t.Error("Unexpected result", r)
+		}
+	})
 }
@@ -0,0 +1,129 @@
+package assassin
+
+import "time"
+
+// KillMethod describes how a player was eliminated.
+type KillMethod int
+
+const (
+	// AssassinationMethod : the player said their contract's KillWord.
+	AssassinationMethod KillMethod = iota
+	// AttackMethod : the player's contract attacked them and they did not counter.
+	AttackMethod
+	// CounterMethod : the player attacked their target, who countered.
+	CounterMethod
+	// DisqualifiedMethod : the player was caught cheating (see AntiCheat). By is the player themselves.
+	DisqualifiedMethod
+)
+
+func (m KillMethod) String() string {
+	switch m {
+	case AssassinationMethod:
+		return "assassination"
+	case AttackMethod:
+		return "attack"
+	case CounterMethod:
+		return "counter"
+	case DisqualifiedMethod:
+		return "disqualification"
+	}
+	return "unknown"
+}
+
+/*
+Event is implemented by all events published by the GameEngine.
+Events carry Player values copied at the time of the event. These include private
+details (KillWords, targets), so take care when passing them on to other players.
+*/
+type Event interface {
+	GameID() ID
+	At() time.Time
+}
+
+// EventInfo contains the details common to all events.
+type EventInfo struct {
+	Game ID
+	Time time.Time
+}
+
+// GameID returns the ID of the game the event occurred in.
+func (i EventInfo) GameID() ID { return i.Game }
+
+// At returns the time the event occurred.
+func (i EventInfo) At() time.Time { return i.Time }
+
+// GameStarted is published when a game begins, before targets are assigned.
+type GameStarted struct {
+	EventInfo
+	Players []Player
+}
+
+/*
+TargetAssigned is published when a player is given a new target, along with a new KillWord.
+A player left without a target has an empty KillWord.
+*/
+type TargetAssigned struct {
+	EventInfo
+	Player, Target Player
+}
+
+// AttackLaunched is published when a player attacks their target by saying their own KillWord.
+type AttackLaunched struct {
+	EventInfo
+	Attacker, Target Player
+}
+
+// AttackCountered is published when the target of an attack says their own KillWord in time.
+type AttackCountered struct {
+	EventInfo
+	Attacker, Target Player
+}
+
+// PlayerAssassinated is published when a player says the KillWord of their contract.
+type PlayerAssassinated struct {
+	EventInfo
+	Player, By Player
+}
+
+// CheatDetected is published when a player's message is flagged by the game's AntiCheat.
+type CheatDetected struct {
+	EventInfo
+	Player  Player
+	Cheat   Cheat
+	Penalty Penalty
+}
+
+// PlayerEliminated is published whenever a player is killed, by whatever method.
+type PlayerEliminated struct {
+	EventInfo
+	Player, By Player
+	Method     KillMethod
+}
+
+// GameEnded is published when a game finishes. Reason is set if the game was stopped early.
+type GameEnded struct {
+	EventInfo
+	Winners []Player
+	Reason  string
+}
+
+// Subscriber interface for receiving events published by the GameEngine.
+type Subscriber interface {
+	Handle(ev Event)
+}
+
+type gameFilter struct {
+	id ID
+	s  Subscriber
+}
+
+func (f *gameFilter) Handle(ev Event) {
+	if ev.GameID() == f.id {
+		f.s.Handle(ev)
+	}
+}
+
+// ForGame returns a Subscriber that passes on to s only the events from the game with given id.
+func ForGame(id ID, s Subscriber) Subscriber {
+	return &gameFilter{id, s}
+}
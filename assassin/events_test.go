@@ -0,0 +1,126 @@
+package assassin
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+type testSubscriber struct {
+	ev chan Event
+}
+
+func newTestSubscriber() *testSubscriber {
+	return &testSubscriber{make(chan Event, 100)}
+}
+
+func (s *testSubscriber) Handle(ev Event) {
+	s.ev <- ev
+}
+
+// wait until n events have been received.
+func (s *testSubscriber) wait(t *testing.T, n int) {
+	var to = timeout(time.Second)
+	for len(s.ev) < n {
+		select {
+		case d := <-to:
+			t.Fatal("Expected", n, "events within", d, "got", len(s.ev))
+		default:
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// events returns the events received so far.
+func (s *testSubscriber) events() []Event {
+	var evs = make([]Event, 0)
+	for {
+		select {
+		case ev := <-s.ev:
+			evs = append(evs, ev)
+		default:
+			return evs
+		}
+	}
+}
+
+// types returns the type names of evs.
+func types(evs []Event) []string {
+	var ts = make([]string, 0)
+	for _, ev := range evs {
+		ts = append(ts, fmt.Sprintf("%T", ev))
+	}
+	return ts
+}
+
+// assigned returns the last TargetAssigned event for each player in evs.
+func assigned(evs []Event) map[ID]TargetAssigned {
+	var as = make(map[ID]TargetAssigned)
+	for _, ev := range evs {
+		if ev, ok := ev.(TargetAssigned); ok {
+			as[ev.Player.ID] = ev
+		}
+	}
+	return as
+}
+
+func TestEvents(t *testing.T) {
+	var clock = testClock()
+	var e = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), clock)
+	var all, one, gone = newTestSubscriber(), newTestSubscriber(), newTestSubscriber()
+	e.Subscribe(all)
+	e.Subscribe(ForGame(2, one))
+	e.Subscribe(gone)
+	e.Unsubscribe(gone)
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4"}, nil), nil)
+	var res = make(chan error)
+	go func() { var _, err = e.Run(g); res <- err }()
+	all.wait(t, 4)
+	var evs = all.events()
+	var as = assigned(evs)
+	var a, b = as[1].Player, as[1].Target
+	var c = as[b.ID].Target
+	// a attacks b, b counters
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: a.ID, Text: a.KillWord})
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: b.ID, Text: b.KillWord})
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	all.wait(t, 4)
+	// c assassinated by saying b's KillWord
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: b.KillWord})
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	var exp = []string{
+		"assassin.GameStarted",
+		"assassin.TargetAssigned", "assassin.TargetAssigned", "assassin.TargetAssigned",
+		"assassin.AttackLaunched",
+		"assassin.AttackCountered",
+		"assassin.PlayerEliminated", "assassin.TargetAssigned",
+		"assassin.PlayerAssassinated", "assassin.PlayerEliminated", "assassin.TargetAssigned",
+		"assassin.GameEnded",
+	}
+	var ts = types(append(evs, all.events()...))
+	if fmt.Sprint(ts) != fmt.Sprint(exp) {
+		t.Error("Unexpected events", ts, "!=", exp)
+	}
+	if ts := types(one.events()); len(ts) != 0 {
+		t.Error("Unexpected events for filtered game", ts)
+	}
+	if ts := types(gone.events()); len(ts) != 0 {
+		t.Error("Unexpected events after unsubscribe", ts)
+	}
+}
+
+func TestKillMethod(t *testing.T) {
+	for m, s := range map[KillMethod]string{
+		AssassinationMethod: "assassination",
+		AttackMethod:        "attack",
+		CounterMethod:       "counter",
+		KillMethod(-1):      "unknown",
+	} {
+		if m.String() != s {
+			t.Error(m, "!=", s)
+		}
+	}
+}
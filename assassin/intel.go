@@ -0,0 +1,92 @@
+package assassin
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// Hint is a kind of intel about a player's contract, the player hunting them.
+type Hint int
+
+const (
+	// InitialHint : the first letter of the contract's name.
+	InitialHint Hint = iota
+	// SuspectsHint : a few players alive, the contract among them, fewer each time it is given.
+	SuspectsHint
+)
+
+// hints are given in turn, one kind each quiet spell.
+var hints = []Hint{InitialHint, SuspectsHint}
+
+// IntelGiven is published when a player is given a hint about their contract, after a spell with no eliminations.
+type IntelGiven struct {
+	EventInfo
+	Player, Contract Player
+	Hint             Hint
+	// Suspects are the players of a SuspectsHint, in order of ID.
+	Suspects []Player
+}
+
+/*
+SetIntel turns on hints for players about who is hunting them, given after d passes with no eliminations,
+and again each time d passes after that. Use 0 to turn them off, as by default.
+*/
+func (g *Game) SetIntel(d time.Duration) {
+	g.settings.Intel = d
+}
+
+/*
+suspects returns n players alive besides p, in order of ID, one of whom is c.
+The others are picked in an order fixed for each game and player, so a shorter list is drawn from a longer one.
+*/
+func (g Game) suspects(p, c ID, n int) []Player {
+	var ids []ID
+	for _, id := range g.ids() {
+		if q := g.players[id]; q.Alive && id != p && id != c {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return g.suspicion(p, ids[i]) < g.suspicion(p, ids[j]) })
+	if len(ids) > n-1 {
+		ids = ids[:n-1]
+	}
+	ids = append(ids, c)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var s = make([]Player, len(ids))
+	for i, id := range ids {
+		s[i] = *g.players[id]
+	}
+	return s
+}
+
+// suspicion ranks q among the players p may suspect.
+func (g Game) suspicion(p, q ID) uint64 {
+	var h = fnv.New64a()
+	fmt.Fprint(h, g.ID, p, q)
+	return h.Sum64()
+}
+
+// handleIntel gives each player alive the next hint about their contract.
+func (r *gameRun) handleIntel() {
+	r.record(r.entry(LogIntel))
+	var h = hints[r.intel%len(hints)]
+	// Nb. each round of hints halves the suspects, down to two.
+	var n = (r.alive - 1) >> uint(r.intel/len(hints)+1)
+	if n < 2 {
+		n = 2
+	}
+	r.intel++
+	r.g.WithPlayers(func(p Player) {
+		var c, ok = p.GetContract()
+		if !ok || !p.Alive || c.ID == p.ID {
+			return
+		}
+		var ev = IntelGiven{EventInfo: r.info(), Player: p, Contract: c, Hint: h}
+		if h == SuspectsHint {
+			ev.Suspects = r.g.suspects(p.ID, c.ID, n)
+		}
+		r.publish(ev)
+	})
+}
@@ -0,0 +1,106 @@
+package assassin
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin/assassintest"
+)
+
+func TestIntel(t *testing.T) {
+	var start = time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	var clock = assassintest.NewClock(start)
+	var ec = make(eventChan, 32)
+	var e = NewGameEngine(LangEn, nil, FixedTiming(time.Hour), clock)
+	e.Subscribe(ec)
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "bee", 3: "Cee", 4: "Dee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5"}, nil), nil)
+	g.SetStartOrder([]ID{1, 3, 2, 4})
+	g.SetIntel(5 * time.Minute)
+	go e.Run(g)
+	defer e.Stop(1, "")
+	// intel reads the hints given in the next quiet spell
+	var intel = func() map[ID]IntelGiven {
+		clock.BlockUntil(1)
+		clock.Advance(5 * time.Minute)
+		var hs = make(map[ID]IntelGiven)
+		for len(hs) < 4 {
+			select {
+			case ev := <-ec:
+				if ev, ok := ev.(IntelGiven); ok {
+					hs[ev.Player.ID] = ev
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Missing intel", hs)
+			}
+		}
+		return hs
+	}
+	var hs = intel()
+	// contracts: Ace by Dee, Cee by Ace, Bee by Cee, Dee by Bee
+	for id, c := range map[ID]ID{1: 4, 3: 1, 2: 3, 4: 2} {
+		if h := hs[id]; h.Hint != InitialHint || h.Contract.ID != c || !h.At().Equal(start.Add(5*time.Minute)) {
+			t.Error("Unexpected intel", h)
+		}
+	}
+	hs = intel()
+	// each player is given their contract and another, picked for them alone
+	var seen = make(map[[2]ID]bool)
+	for id, c := range map[ID]ID{1: 4, 3: 1, 2: 3, 4: 2} {
+		var h = hs[id]
+		if h.Hint != SuspectsHint || len(h.Suspects) != 2 || h.Suspects[0].ID != c && h.Suspects[1].ID != c || h.Suspects[0].ID == id || h.Suspects[1].ID == id {
+			t.Error("Unexpected intel for", id, h)
+			continue
+		}
+		seen[[2]ID{h.Suspects[0].ID, h.Suspects[1].ID}] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Same suspects for every player", seen)
+	}
+	t.Run("Suspects", func(t *testing.T) {
+		var names = map[ID]string{}
+		for id := ID(1); id <= 9; id++ {
+			names[id] = fmt.Sprint("P", id)
+		}
+		var g = NewGame(1, names, NewWordList([]string{"kw1"}, nil), nil)
+		g.Start()
+		var ids = func(ps []Player) map[ID]bool {
+			var m = make(map[ID]bool)
+			for _, p := range ps {
+				m[p.ID] = true
+			}
+			return m
+		}
+		var four, two = ids(g.suspects(1, 5, 4)), ids(g.suspects(1, 5, 2))
+		if len(four) != 4 || len(two) != 2 || !four[5] || !two[5] || four[1] {
+			t.Error("Unexpected suspects", four, two)
+		}
+		for id := range two {
+			if !four[id] {
+				t.Error("Suspects not narrowed", four, two)
+			}
+		}
+		if s := g.suspects(1, 5, 20); len(s) != 8 {
+			t.Error("Unexpected suspects", s)
+		}
+		var differ bool
+		for id := ID(2); id <= 9 && !differ; id++ {
+			if id != 5 {
+				differ = !reflect.DeepEqual(ids(g.suspects(id, 5, 4)), four)
+			}
+		}
+		if !differ {
+			t.Error("Same suspects for every player", four)
+		}
+	})
+	t.Run("Render", func(t *testing.T) {
+		var mh = &recordingMessageHandler{}
+		var r = NewMessageRenderer(LangEn, mh)
+		r.Handle(IntelGiven{Player: Player{ID: 3}, Contract: Player{ID: 1, Name: "bee"}, Hint: InitialHint})
+		r.Handle(IntelGiven{Player: Player{ID: 3}, Hint: SuspectsHint, Suspects: []Player{{ID: 1, Name: "bee"}, {ID: 2, Name: "Dee"}}})
+		if len(mh.n) != 2 || mh.n[0] != LangEn.Fmt(LangEn.IN, "B") || mh.n[1] != LangEn.Fmt(LangEn.IS, []string{"bee", "Dee"}) {
+			t.Error("Unexpected notifications", mh.n)
+		}
+	})
+}
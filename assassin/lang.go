@@ -4,9 +4,13 @@ import "fmt"
 
 // Lang represents localised template strings for the game
 type Lang struct {
-	EIP,
-	GS, GE, GW, GWM, GD,
-	PA, PD, PT, PCS, PAS string
+	EIP, ENR,
+	GS, GE, GQ, GQR, GW, GWM, GD, GDQ,
+	PA, PD, PT, PCS, PAS,
+	AW, AML, AFK, AAR,
+	IN, IS,
+	LB, LBE,
+	CJ, CJT, CAJ, CL, CNJ, CP, CNP, CFP, CFT, CA, CS, CU, CH string
 }
 
 // Fmt should be used to format a template string when substitutions are required.
@@ -17,14 +21,44 @@ func (l Lang) Fmt(t string, a ...interface{}) string {
 // LangEn : English template strings
 var LangEn = Lang{
 	EIP: "Game already in progress",
+	ENR: "Game not running",
 	GS:  "The game has begun.",
 	GE:  "The game has ended.",
+	GQ:  "The game has been stopped: %v.",
+	GQR: "the game was cancelled",
 	GW:  "%v wins.",
 	GWM: "Surviving this time: %v.",
 	GD:  "%v has been assassinated.",
+	GDQ: "%v has been disqualified for cheating.",
 	PA:  "You are alive.",
 	PD:  "You have been assassinated.",
 	PT:  "Your target is %v. Your KillWord is %v.",
 	PAS: "Your attack was successful.",
 	PCS: "Your counterattack was successful.",
+	AW:  "Your message was ignored: %v.",
+	AML: "it was too long",
+	AFK: "it said too many KillWords",
+	AAR: "you are attacking too often",
+	IN:  "Intel: the name of the player hunting you begins with %v.",
+	IS:  "Intel: the player hunting you is one of %v.",
+	LB:  "Leaderboard:",
+	LBE: "%v. %v: %v wins, %v kills, %v deaths, average survival %v.",
+	CJ:  "%v has joined the next game.",
+	CJT: "%v has joined team %v for the next game.",
+	CAJ: "%v has already joined.",
+	CL:  "%v has left the next game.",
+	CNJ: "%v has not joined.",
+	CP:  "Players: %v.",
+	CNP: "Nobody has joined yet.",
+	CFP: "At least %v players are needed to start.",
+	CFT: "At least two teams are needed to start.",
+	CA:  "Only admins can %v.",
+	CS:  "Still alive: %v.",
+	CU:  "Unknown command %v, try %vhelp.",
+	CH:  "Commands: %v.",
+}
+
+// Langs lists the available template sets by language code.
+var Langs = map[string]Lang{
+	"en": LangEn,
 }
@@ -0,0 +1,241 @@
+package assassin
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// LogKind identifies the input recorded by a LogEntry.
+type LogKind int
+
+const (
+	// LogCreate records the players in a game, and its Settings.
+	LogCreate LogKind = iota
+	// LogStart records the order in which players were linked into the chain.
+	LogStart
+	// LogWord records a KillWord handed out by the WordGenerator.
+	LogWord
+	// LogTalk records talk accepted by the game.
+	LogTalk
+	// LogTimer records an attack timer firing.
+	LogTimer
+	// LogQuit records the game being ended early.
+	LogQuit
+	// LogIntel records hints being given after a quiet spell.
+	LogIntel
+)
+
+/*
+LogEntry records a single input seen by the GameEngine.
+Only the fields relevant to the Kind are set.
+*/
+type LogEntry struct {
+	Game     ID
+	Kind     LogKind
+	Time     time.Time
+	Players  map[ID]string `json:",omitempty"`
+	Settings *Settings     `json:",omitempty"`
+	Order    []ID          `json:",omitempty"`
+	Word     string        `json:",omitempty"`
+	Talk     *Talk         `json:",omitempty"`
+	Attack   int           `json:",omitempty"`
+	Reason   string        `json:",omitempty"`
+}
+
+/*
+GameLog interface for an append-only log of game inputs.
+
+	Append adds an entry to the end of the log.
+*/
+type GameLog interface {
+	Append(l LogEntry) error
+}
+
+// MemoryLog is a GameLog kept in memory.
+type MemoryLog struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// Append adds l to the log.
+func (m *MemoryLog) Append(l LogEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, l)
+	return nil
+}
+
+// Entries returns a copy of the entries logged so far.
+func (m *MemoryLog) Entries() []LogEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]LogEntry(nil), m.entries...)
+}
+
+// JSONLog is a GameLog writing each entry as a line of JSON.
+type JSONLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONLog creates a JSONLog writing to w.
+func NewJSONLog(w io.Writer) *JSONLog {
+	return &JSONLog{enc: json.NewEncoder(w)}
+}
+
+// Append writes l to the log.
+func (j *JSONLog) Append(l LogEntry) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.enc.Encode(l)
+}
+
+// ReadLog reads back all entries written by a JSONLog.
+func ReadLog(r io.Reader) ([]LogEntry, error) {
+	var (
+		d = json.NewDecoder(r)
+		l = make([]LogEntry, 0)
+	)
+	for {
+		var e LogEntry
+		if err := d.Decode(&e); err == io.EOF {
+			return l, nil
+		} else if err != nil {
+			return l, err
+		}
+		l = append(l, e)
+	}
+}
+
+// recordingWords is a WordGenerator passing on each word generated by kwg to f.
+type recordingWords struct {
+	kwg WordGenerator
+	f   func(w string)
+}
+
+func (r *recordingWords) Next() string {
+	var w = r.kwg.Next()
+	r.f(w)
+	return w
+}
+
+// Words lists the words of kwg, if it can.
+func (r *recordingWords) Words() []string {
+	if wl, ok := r.kwg.(wordLister); ok {
+		return wl.Words()
+	}
+	return nil
+}
+
+// replayWords is a WordGenerator handing out words in the order they were logged.
+type replayWords struct {
+	words []string
+}
+
+func (r *replayWords) Next() string {
+	if len(r.words) == 0 {
+		return ""
+	}
+	var w = r.words[0]
+	r.words = r.words[1:]
+	return w
+}
+
+// ErrNoGameInLog is returned when replaying a game that is not in the log.
+var ErrNoGameInLog = errors.New("Game not found in log")
+
+/*
+Replayer rebuilds the state of a game from its log, one entry at a time.
+Replay produces the same events, and so the same eliminations, as the live game.
+*/
+type Replayer struct {
+	r       *gameRun
+	entries []LogEntry
+	pos     int
+	events  []Event
+}
+
+// NewReplayer creates a Replayer for game id from the entries of a log.
+func NewReplayer(entries []LogEntry, id ID) (*Replayer, error) {
+	var (
+		rp    = &Replayer{entries: make([]LogEntry, 0)}
+		words = new(replayWords)
+		g     *Game
+	)
+	for _, l := range entries {
+		if l.Game != id {
+			continue
+		}
+		switch l.Kind {
+		case LogCreate:
+			if g == nil {
+				g = NewGame(id, l.Players, words, nil)
+				if l.Settings != nil {
+					g.settings = *l.Settings
+				}
+			}
+		case LogWord:
+			words.words = append(words.words, l.Word)
+		}
+		rp.entries = append(rp.entries, l)
+	}
+	if g == nil {
+		return nil, ErrNoGameInLog
+	}
+	rp.r = newGameRun(g)
+	rp.r.publish = func(ev Event) { rp.events = append(rp.events, ev) }
+	return rp, nil
+}
+
+// Game returns the game as rebuilt so far.
+func (rp *Replayer) Game() *Game {
+	return rp.r.g
+}
+
+// Events returns the events published so far.
+func (rp *Replayer) Events() []Event {
+	return append([]Event(nil), rp.events...)
+}
+
+// Pos returns the number of entries replayed so far.
+func (rp *Replayer) Pos() int {
+	return rp.pos
+}
+
+// Step replays the next entry in the log. Returns false when there are no more entries.
+func (rp *Replayer) Step() bool {
+	if rp.pos >= len(rp.entries) {
+		return false
+	}
+	var l = rp.entries[rp.pos]
+	rp.pos++
+	rp.r.now = func() time.Time { return l.Time }
+	switch l.Kind {
+	case LogStart:
+		rp.r.begin(l.Order)
+	case LogTalk:
+		if l.Talk != nil {
+			rp.r.handleTalk(*l.Talk)
+		}
+	case LogTimer:
+		rp.r.handleAttack(l.Attack)
+	case LogQuit:
+		rp.r.quit(l.Reason)
+	case LogIntel:
+		rp.r.handleIntel()
+	}
+	if rp.r.over() && l.Kind != LogCreate && l.Kind != LogWord {
+		rp.r.end()
+		rp.pos = len(rp.entries)
+	}
+	return true
+}
+
+// ReplayTo replays entries until n have been replayed, or the log runs out.
+func (rp *Replayer) ReplayTo(n int) {
+	for rp.pos < n && rp.Step() {
+	}
+}
@@ -0,0 +1,206 @@
+package assassin
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// eliminations returns the victim, killer and method of each PlayerEliminated event.
+func eliminations(evs []Event) []string {
+	var el = make([]string, 0)
+	for _, ev := range evs {
+		if ev, ok := ev.(PlayerEliminated); ok {
+			el = append(el, fmt.Sprint(ev.Player.ID, ev.By.ID, ev.Method))
+		}
+	}
+	return el
+}
+
+func TestReplay(t *testing.T) {
+	var (
+		clock = testClock()
+		e     = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), clock)
+		ml    = new(MemoryLog)
+		sub   = newTestSubscriber()
+		g     = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4"}, nil), nil)
+		res   = make(chan error)
+	)
+	e.SetLog(ml)
+	e.Subscribe(sub)
+	go func() { var _, err = e.Run(g); res <- err }()
+	sub.wait(t, 4)
+	var evs = sub.events()
+	var as = assigned(evs)
+	var a, b = as[1].Player, as[1].Target
+	var c = as[b.ID].Target
+	// a attacks b, who fails to counter
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: a.ID, Text: a.KillWord})
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	sub.wait(t, 3)
+	evs = append(evs, sub.events()...)
+	var kw = assigned(evs)[a.ID].Player.KillWord
+	// c is assassinated by saying the KillWord of a, who now hunts c
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: "ignored " + b.KillWord})
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: kw})
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	var live = eliminations(append(evs, sub.events()...))
+
+	var buf = new(bytes.Buffer)
+	var jl = NewJSONLog(buf)
+	for _, l := range ml.Entries() {
+		jl.Append(l)
+	}
+	var entries, err = ReadLog(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(ml.Entries()) {
+		t.Fatal("Read", len(entries), "entries, expected", len(ml.Entries()))
+	}
+
+	t.Run("ReplayTo", func(t *testing.T) {
+		var rp, err = NewReplayer(entries, g.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// create, start, 3 words, attack talk, timer
+		rp.ReplayTo(7)
+		var rg = rp.Game()
+		if rg.Status() != 2 || rg.players[b.ID].Alive {
+			t.Error("Unexpected replayed state after attack", rg.Status(), *rg.players[b.ID])
+		}
+		if rg.players[a.ID].target.ID != c.ID || rg.players[a.ID].KillWord != kw {
+			t.Error("Unexpected replayed chain after attack", *rg.players[a.ID])
+		}
+	})
+	t.Run("Step", func(t *testing.T) {
+		var rp, err = NewReplayer(entries, g.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rp.Step() {
+		}
+		if el := eliminations(rp.Events()); fmt.Sprint(el) != fmt.Sprint(live) {
+			t.Error("Replayed eliminations", el, "!= live", live)
+		}
+		if rp.Game().Status() != g.Status() {
+			t.Error("Replayed status", rp.Game().Status(), "!= live", g.Status())
+		}
+	})
+	t.Run("NoGame", func(t *testing.T) {
+		if _, err := NewReplayer(entries, 2); err != ErrNoGameInLog {
+			t.Error("Expected", ErrNoGameInLog, "got", err)
+		}
+	})
+}
+
+func TestReplaySettings(t *testing.T) {
+	var (
+		e   = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), testClock())
+		ml  = new(MemoryLog)
+		sub = newTestSubscriber()
+		g   = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"apple", "berry", "cherry"}, nil), nil)
+		res = make(chan error)
+	)
+	g.SetMatcher(WordMatcher{Stem: true})
+	e.SetLog(ml)
+	e.Subscribe(sub)
+	go func() { var _, err = e.Run(g); res <- err }()
+	sub.wait(t, 4)
+	var evs = sub.events()
+	var a, b, c = evs[1].(TargetAssigned).Player, evs[1].(TargetAssigned).Target, Player{}
+	for _, ev := range evs[1:] {
+		if ev.(TargetAssigned).Target.ID == a.ID {
+			c = ev.(TargetAssigned).Player
+		}
+	}
+	// b and then c are assassinated by saying a plural of the KillWord of a
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: b.ID, Text: a.KillWord + "s"})
+	sub.wait(t, 3)
+	evs = append(evs, sub.events()...)
+	var ta, ok = evs[6].(TargetAssigned)
+	if !ok || ta.Player.ID != a.ID {
+		t.Fatal("Unexpected event", evs[6])
+	}
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: ta.Player.KillWord + "s"})
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	var buf = new(bytes.Buffer)
+	var jl = NewJSONLog(buf)
+	for _, l := range ml.Entries() {
+		jl.Append(l)
+	}
+	var entries, err = ReadLog(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rp, err := NewReplayer(entries, g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rp.Step() {
+	}
+	sub.wait(t, 2)
+	var live = eliminations(append(evs, sub.events()...))
+	if el := eliminations(rp.Events()); len(el) != 2 || fmt.Sprint(el) != fmt.Sprint(live) {
+		t.Error("Replayed eliminations", el, "!= live", live)
+	}
+}
+
+// intel returns the player, contract, hint and suspects of each IntelGiven event.
+func intel(evs []Event) []string {
+	var in = make([]string, 0)
+	for _, ev := range evs {
+		if ev, ok := ev.(IntelGiven); ok {
+			var s = make([]ID, 0, len(ev.Suspects))
+			for _, p := range ev.Suspects {
+				s = append(s, p.ID)
+			}
+			in = append(in, fmt.Sprint(ev.Player.ID, ev.Contract.ID, ev.Hint, s))
+		}
+	}
+	return in
+}
+
+func TestReplayIntel(t *testing.T) {
+	var (
+		clock = testClock()
+		e     = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), clock)
+		ml    = new(MemoryLog)
+		sub   = newTestSubscriber()
+		g     = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee", 4: "Dee", 5: "Eee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5"}, nil), nil)
+		res   = make(chan error)
+	)
+	g.SetIntel(5 * time.Minute)
+	e.SetLog(ml)
+	e.Subscribe(sub)
+	go func() { var _, err = e.Run(g); res <- err }()
+	var evs []Event
+	// the start, and two quiet spells of 5 hints each
+	for _, n := range []int{11, 5} {
+		clock.BlockUntil(1)
+		clock.Advance(5 * time.Minute)
+		sub.wait(t, n)
+		evs = append(evs, sub.events()...)
+	}
+	e.Stop(g.ID, "")
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	var live = intel(evs)
+	rp, err := NewReplayer(ml.Entries(), g.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rp.Step() {
+	}
+	if in := intel(rp.Events()); len(in) != 10 || fmt.Sprint(in) != fmt.Sprint(live) {
+		t.Error("Replayed intel", in, "!= live", live)
+	}
+}
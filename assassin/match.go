@@ -0,0 +1,199 @@
+package assassin
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Matcher decides whether a chat message says a KillWord.
+type Matcher interface {
+	Match(text, word string) bool
+}
+
+// MatcherFunc is a function used as a Matcher.
+type MatcherFunc func(text, word string) bool
+
+// Match calls f(text, word).
+func (f MatcherFunc) Match(text, word string) bool { return f(text, word) }
+
+// exactMatcher matches a KillWord anywhere in a message, exactly as written.
+type exactMatcher struct{}
+
+func (exactMatcher) Match(text, word string) bool { return strings.Contains(text, word) }
+
+// ExactMatcher matches a KillWord anywhere in a message, exactly as written.
+var ExactMatcher Matcher = exactMatcher{}
+
+/*
+WordMatcher matches a KillWord said as a whole word, ignoring case, punctuation and accents,
+so "Banana!" and "BANÀNA" say "banana" but "concatenate" does not say "cat".
+Text is folded with a fixed table of accented Latin letters, ligatures and full-width forms, so "ﬁsh" and "ｆｉｓｈ" say "fish";
+other letters are only folded to lower case.
+*/
+type WordMatcher struct {
+	// Stem matches inflected forms of the word, such as plurals ("bananas") and -ed or -ing endings.
+	Stem bool `json:"stem"`
+	// Leet matches look-alike digits, symbols and letters of other alphabets, such as "b4n4n4" or Cyrillic "а".
+	Leet bool `json:"leet"`
+}
+
+// DefaultMatcher is the Matcher of games not given another with Game.SetMatcher.
+var DefaultMatcher Matcher = WordMatcher{}
+
+// Match reports whether text says word.
+func (m WordMatcher) Match(text, word string) bool {
+	return m.says(text, m.tokens(text), word)
+}
+
+// says reports whether text, split into tokens tt, says word.
+func (m WordMatcher) says(text string, tt []string, word string) bool {
+	var wt = m.tokens(word)
+	if len(wt) == 0 {
+		// Nb. a KillWord of only punctuation can still be said.
+		return strings.Contains(text, word)
+	}
+	for i := 0; i+len(wt) <= len(tt); i++ {
+		var j = 0
+		for j < len(wt) && (tt[i+j] == wt[j] || m.Stem && inflects(tt[i+j], wt[j])) {
+			j++
+		}
+		if j == len(wt) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+sayer returns a function reporting whether text says a word, for checking many words against the same text.
+The text is only split into words once, when the game's Matcher is a WordMatcher.
+*/
+func (g Game) sayer(text string) func(word string) bool {
+	var m = g.settings.Matcher
+	if m == nil {
+		m = DefaultMatcher
+	}
+	if wm, ok := m.(WordMatcher); ok {
+		var tt = wm.tokens(text)
+		return func(word string) bool { return wm.says(text, tt, word) }
+	}
+	return func(word string) bool { return m.Match(text, word) }
+}
+
+// tokens splits s into its words, folded for comparison.
+func (m WordMatcher) tokens(s string) []string {
+	var (
+		ts []string
+		b  strings.Builder
+	)
+	var flush = func() {
+		var t = b.String()
+		b.Reset()
+		if m.Leet {
+			t = unleet(t)
+		}
+		if t != "" {
+			ts = append(ts, t)
+		}
+	}
+	for _, r := range s {
+		for _, f := range fold(r, m.Leet) {
+			switch {
+			case unicode.Is(unicode.Mn, f):
+				// Nb. combining accents are dropped, as accented letters are.
+			case unicode.IsLetter(f) || unicode.IsDigit(f) || m.Leet && leet[f] != 0:
+				b.WriteRune(f)
+			default:
+				flush()
+			}
+		}
+	}
+	flush()
+	return ts
+}
+
+// foldings maps accented letters and ligatures to plain lower case letters.
+var foldings = func() map[rune]string {
+	var m = make(map[rune]string)
+	for plain, rs := range map[string]string{
+		"a": "àáâãäåāăą", "c": "çćĉċč", "d": "ďđð", "e": "èéêëēĕėęě", "g": "ĝğġģ", "h": "ĥħ",
+		"i": "ìíîïĩīĭįı", "j": "ĵ", "k": "ķ", "l": "ĺļľŀł", "n": "ñńņňŉ", "o": "òóôõöøōŏő",
+		"r": "ŕŗř", "s": "śŝşšſ", "t": "ţťŧ", "u": "ùúûüũūŭůűų", "w": "ŵ", "y": "ýÿŷ", "z": "źżž",
+		"ae": "æ", "oe": "œ", "ss": "ß", "th": "þ", "ff": "ﬀ", "fi": "ﬁ", "fl": "ﬂ", "ffi": "ﬃ", "ffl": "ﬄ", "st": "ﬅﬆ",
+	} {
+		for _, r := range rs {
+			m[r] = plain
+		}
+	}
+	return m
+}()
+
+// homoglyphs maps lower case Cyrillic and Greek letters to the Latin letters they look like.
+var homoglyphs = map[rune]rune{
+	'а': 'a', 'в': 'b', 'с': 'c', 'е': 'e', 'һ': 'h', 'н': 'h', 'і': 'i', 'ј': 'j', 'к': 'k', 'м': 'm',
+	'о': 'o', 'р': 'p', 'ѕ': 's', 'т': 't', 'х': 'x', 'у': 'y',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// fold r to the plain lower case letters it stands for, including look-alikes if homoglyph is set.
+func fold(r rune, homoglyph bool) string {
+	if r >= 0xFF01 && r <= 0xFF5E {
+		// full-width forms of ASCII
+		r -= 0xFEE0
+	}
+	r = unicode.ToLower(r)
+	if s, ok := foldings[r]; ok {
+		return s
+	}
+	if h, ok := homoglyphs[r]; ok && homoglyph {
+		r = h
+	}
+	return string(r)
+}
+
+/*
+leet maps digits and symbols to the letters they stand in for.
+Look-alike letters share a form too, as "1" may be "i" or "l".
+*/
+var leet = map[rune]rune{
+	'0': 'o', '1': 'i', '!': 'i', '|': 'i', 'l': 'i', '3': 'e', '4': 'a', '@': 'a',
+	'5': 's', '$': 's', '7': 't', '+': 't', '8': 'b', '9': 'g',
+}
+
+// unleet replaces the look-alikes in t, after trimming any exclamation it starts or ends with, like "banana!".
+func unleet(t string) string {
+	t = strings.Trim(t, "!|")
+	return strings.Map(func(r rune) rune {
+		if l, ok := leet[r]; ok {
+			return l
+		}
+		return r
+	}, t)
+}
+
+// inflects reports whether t is an English inflection of w, such as a plural ("bananas") or -ed or -ing ending.
+func inflects(t, w string) bool {
+	if len(w) < 3 {
+		return false
+	}
+	var forms = []string{w + "s", w + "es", w + "ed", w + "ing"}
+	var last, rest = w[len(w)-1], w[:len(w)-1]
+	switch {
+	case last == 'y':
+		forms = append(forms, rest+"ies", rest+"ied")
+	case last == 'e':
+		forms = append(forms, w+"d", rest+"ing")
+	case last == 's' && !strings.HasSuffix(w, "ss"):
+		// a plural word matches its singular
+		forms = append(forms, rest)
+	case !strings.ContainsRune("aeiouwxy", rune(last)) && strings.ContainsRune("aeiou", rune(rest[len(rest)-1])):
+		// doubling the consonant, as in "stopped"
+		forms = append(forms, w+string(last)+"ed", w+string(last)+"ing")
+	}
+	for _, f := range forms {
+		if t == f {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package assassin
+
+import "testing"
+
+func TestWordMatcher(t *testing.T) {
+	var cases = []struct {
+		m     WordMatcher
+		text  string
+		word  string
+		match bool
+	}{
+		{WordMatcher{}, "I like banana", "banana", true},
+		{WordMatcher{}, "Banana!", "banana", true},
+		{WordMatcher{}, "BANÀNA split", "banana", true},
+		{WordMatcher{}, "banána", "banana", true},
+		{WordMatcher{}, "ｂａｎａｎａ", "banana", true},
+		{WordMatcher{}, "the café is open", "cafe", true},
+		{WordMatcher{}, "the ﬁsh", "fish", true},
+		{WordMatcher{}, "Straße", "strasse", true},
+		{WordMatcher{}, "concatenate", "cat", false},
+		{WordMatcher{}, "bananas", "banana", false},
+		{WordMatcher{}, "b4n4n4", "banana", false},
+		{WordMatcher{}, "ice cream, please", "ice cream", true},
+		{WordMatcher{}, "ice and cream", "ice cream", false},
+		{WordMatcher{}, "well ?!", "?!", true},
+		{WordMatcher{Stem: true}, "two bananas", "banana", true},
+		{WordMatcher{Stem: true}, "the boxes", "box", true},
+		{WordMatcher{Stem: true}, "the cherries", "cherry", true},
+		{WordMatcher{Stem: true}, "she baked", "bake", true},
+		{WordMatcher{Stem: true}, "stopping", "stop", true},
+		{WordMatcher{Stem: true}, "one apple", "apples", true},
+		{WordMatcher{Stem: true}, "concatenates", "cat", false},
+		{WordMatcher{Stem: true}, "catering", "cat", false},
+		{WordMatcher{Leet: true}, "b4n4n4", "banana", true},
+		{WordMatcher{Leet: true}, "B@N@N@!", "banana", true},
+		{WordMatcher{Leet: true}, "l33t", "leet", true},
+		{WordMatcher{Leet: true}, "банана", "banana", false},
+		{WordMatcher{Leet: true}, "bаnаnа", "banana", true},
+		{WordMatcher{Leet: true}, "kw1", "kw1", true},
+		{WordMatcher{Leet: true, Stem: true}, "b4n4n45", "banana", true},
+	}
+	for _, c := range cases {
+		if m := c.m.Match(c.text, c.word); m != c.match {
+			t.Errorf("%+v matched %q in %q: %v", c.m, c.word, c.text, m)
+		}
+	}
+}
+
+func TestGameMatcher(t *testing.T) {
+	var play = func(m Matcher, text string) int {
+		var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"apple"}, nil), nil)
+		g.SetMatcher(m)
+		var r = newGameRun(g)
+		r.begin([]ID{1, 2, 3})
+		// Bee says the KillWord of Ace, their contract
+		r.handleTalk(Talk{Game: 1, Speaker: 2, Text: text})
+		return r.alive
+	}
+	if a := play(nil, "An APPLE a day"); a != 2 {
+		t.Error("Default matcher left", a, "alive")
+	}
+	if a := play(ExactMatcher, "An APPLE a day"); a != 3 {
+		t.Error("Exact matcher left", a, "alive")
+	}
+	if a := play(WordMatcher{Stem: true}, "Apples"); a != 2 {
+		t.Error("Stemming matcher left", a, "alive")
+	}
+}
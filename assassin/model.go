@@ -1,7 +1,8 @@
 package assassin
 
 import (
-	"math/rand"
+	"errors"
+	"sort"
 )
 
 // ID == identifier, used to uniquely identify Players/Games.
@@ -79,21 +80,30 @@ func (p *Player) SetEliminated() error {
 		return &PlayerDeadError{"Player is already dead"}
 	}
 	p.Alive = false
-	p.contract.SetTarget(p.target)
+	if p.contract != nil {
+		p.contract.SetTarget(p.target)
+	}
 	return nil
 }
 
 // Game contains game state information.
 type Game struct {
 	ID
-	players map[ID]*Player
+	players  map[ID]*Player
+	channels []string
+	kwg      WordGenerator
+	order    []ID
+	rand     Rand
+	settings Settings
 }
 
-// NewGame creates a new Game instance.
-func NewGame(id ID, playerList map[ID]string, kwg WordGenerator) *Game {
+// NewGame creates a new Game instance, shuffling the starting order with r. Use nil for the shared source.
+func NewGame(id ID, playerList map[ID]string, kwg WordGenerator, r Rand) *Game {
 	var g = &Game{
 		ID:      id,
-		players: make(map[ID]*Player, len(playerList))}
+		players: make(map[ID]*Player, len(playerList)),
+		kwg:     kwg,
+		rand:    r}
 	for id, name := range playerList {
 		g.players[id] = NewPlayer(id, name, kwg)
 	}
@@ -109,11 +119,77 @@ func (g Game) GetPlayer(id ID) (p Player, ok bool) {
 	return
 }
 
-// WithPlayers loops over all players in game (alive and dead).
+// ids returns the IDs of all players in game, in ascending order.
+func (g Game) ids() []ID {
+	var ids = make([]ID, 0, len(g.players))
+	for id := range g.players {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// WithPlayers loops over all players in game (alive and dead), in order of ID.
 func (g Game) WithPlayers(f func(p Player)) {
+	for _, id := range g.ids() {
+		f(*g.players[id])
+	}
+}
+
+// SetWordGenerator sets the WordGenerator used to hand out KillWords to all players in game.
+func (g *Game) SetWordGenerator(kwg WordGenerator) {
+	g.kwg = kwg
 	for _, p := range g.players {
-		f(*p)
+		p.kwg = kwg
+	}
+}
+
+// SetMatcher sets the Matcher deciding whether talk says a KillWord. Use nil for DefaultMatcher.
+func (g *Game) SetMatcher(m Matcher) {
+	g.settings.Matcher = m
+}
+
+// says reports whether text says KillWord word.
+func (g Game) says(text, word string) bool {
+	if g.settings.Matcher == nil {
+		return DefaultMatcher.Match(text, word)
+	}
+	return g.settings.Matcher.Match(text, word)
+}
+
+// SetAntiCheat sets the checks for players fishing for KillWords. Use nil to turn them off, as by default.
+func (g *Game) SetAntiCheat(ac *AntiCheat) {
+	if ac != nil {
+		var c = *ac
+		ac = &c
+	}
+	g.settings.AntiCheat = ac
+}
+
+/*
+SetChannels sets the chat channels in which talk counts as in-game speech.
+A game with no channels accepts talk from any channel, provided it is addressed to the game by ID.
+*/
+func (g *Game) SetChannels(ch ...string) {
+	g.channels = append([]string(nil), ch...)
+}
+
+// Channels returns the chat channels configured for the game.
+func (g Game) Channels() []string {
+	return append([]string(nil), g.channels...)
+}
+
+// InChannel reports whether talk in channel ch counts as in-game speech.
+func (g Game) InChannel(ch string) bool {
+	if len(g.channels) == 0 {
+		return true
+	}
+	for _, c := range g.channels {
+		if c == ch {
+			return true
+		}
 	}
+	return false
 }
 
 /*
@@ -158,23 +234,73 @@ func (g *Game) ResolvePlayerCounter(pid, cid ID) (Player, bool) {
 
 /*
 Start starts the game, assigning player targets.
-Nb. This function makes use of rand. Do not forget to Seed!
+Nb. Unless a start order is set, this function makes use of the game's Rand.
 */
 func (g *Game) Start() {
-	/*
-		Players are assigned targets in such a way as to form a circular chain.
-		Here, we use a random permutation to determine the indices (il) in the player list (pl) to link up. We then loop through once and SetTarget accordingly.
-	*/
+	g.startOrder(g.startingOrder())
+}
+
+// ErrInvalidOrder is returned when a start order does not list every player in the game exactly once.
+var ErrInvalidOrder = errors.New("Start order must list every player exactly once")
+
+/*
+SetStartOrder sets the order players are linked into the chain when the game starts, each targeting the next.
+By default the order is random.
+*/
+func (g *Game) SetStartOrder(order []ID) error {
+	var seen = make(map[ID]bool, len(order))
+	for _, id := range order {
+		if _, ok := g.players[id]; !ok || seen[id] {
+			return ErrInvalidOrder
+		}
+		seen[id] = true
+	}
+	if len(seen) != len(g.players) {
+		return ErrInvalidOrder
+	}
+	g.order = append([]ID(nil), order...)
+	return nil
+}
+
+// startingOrder returns the order set with SetStartOrder, or else a random order.
+func (g Game) startingOrder() []ID {
+	if g.order != nil {
+		return append([]ID(nil), g.order...)
+	}
+	return g.shuffled()
+}
+
+// shuffled returns the IDs of all players in game, in random order.
+func (g Game) shuffled() []ID {
 	var (
-		l  = len(g.players)
-		il = rand.Perm(l)
-		pl = make([]*Player, 0, l)
+		ids = g.ids()
+		il  = sharedRand(g.rand).Perm(len(ids))
+		o   = make([]ID, len(ids))
 	)
-	for _, p := range g.players {
-		pl = append(pl, p)
+	for i, j := range il {
+		o[i] = ids[j]
 	}
-	for i := 0; i < len(g.players); i++ {
-		pl[il[i]].SetTarget(pl[il[(i+1)%l]])
+	return o
+}
+
+// startOrder assigns player targets, linking each player in order to the next, or as the game's TargetAssigner deals them.
+func (g *Game) startOrder(order []ID) {
+	if g.settings.Assigner != nil {
+		var links = g.settings.Assigner.Assign(order)
+		for _, id := range order {
+			if t, ok := links[id]; ok {
+				g.players[id].SetTarget(g.players[t])
+			}
+		}
+		return
+	}
+	/*
+		Players are assigned targets in such a way as to form a circular chain.
+		We loop through the order once and SetTarget accordingly.
+	*/
+	var l = len(order)
+	for i := 0; i < l; i++ {
+		g.players[order[i]].SetTarget(g.players[order[(i+1)%l]])
 	}
 }
 
@@ -73,13 +73,13 @@ func TestPlayer(t *testing.T) {
 func TestGame(t *testing.T) {
 	var (
 		pl = map[ID]string{1: "A", 2: "B", 3: "C"}
-		wg = NewWordList([]string{"aaaa", "bbbb", "cccc"})
+		wg = NewWordList([]string{"aaaa", "bbbb", "cccc"}, nil)
 		g  *Game
 	)
 
 	// Test init
 	t.Run("NewGame", func(t *testing.T) {
-		g = NewGame(1, pl, wg)
+		g = NewGame(1, pl, wg, nil)
 		if g.ID != 1 || len(g.players) != 3 {
 			t.Error(g, "not initialised as expected")
 		}
@@ -112,6 +112,32 @@ func TestGame(t *testing.T) {
 		}
 	})
 
+	t.Run("Channels", func(t *testing.T) {
+		if !g.InChannel("#any") {
+			t.Error("Game without channels should accept any channel")
+		}
+		g.SetChannels("#a", "#b")
+		if !g.InChannel("#b") || g.InChannel("#c") {
+			t.Error("Unexpected InChannel response for", g.Channels())
+		}
+		g.SetChannels()
+	})
+
+	t.Run("SetStartOrder", func(t *testing.T) {
+		for _, o := range [][]ID{{1, 2}, {1, 2, 2}, {1, 2, 4}, {1, 2, 3, 4}} {
+			if err := g.SetStartOrder(o); err != ErrInvalidOrder {
+				t.Error("Expected", ErrInvalidOrder, "for", o, "got", err)
+			}
+		}
+		if err := g.SetStartOrder([]ID{3, 1, 2}); err != nil {
+			t.Error(err)
+		}
+		if o := g.startingOrder(); len(o) != 3 || o[0] != 3 || o[1] != 1 || o[2] != 2 {
+			t.Error("Unexpected starting order", o)
+		}
+		g.order = nil
+	})
+
 	// Test start
 	t.Run("Start", func(t *testing.T) {
 		g.Start()
@@ -0,0 +1,170 @@
+package assassin
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	// DefaultRating is the rating given to new players.
+	DefaultRating = 1500.0
+	// DefaultRatingK is the default maximum rating change from a single pairwise outcome.
+	DefaultRatingK = 32.0
+)
+
+// Rating is a player's skill rating.
+type Rating struct {
+	Rating float64 `json:"rating"`
+	Games  int     `json:"games"`
+}
+
+/*
+Ratings keeps an Elo-style skill rating for each player, updated from finished games.
+Each game is scored as a set of pairwise outcomes:
+
+	each kill is a win for the killer over the victim;
+	each player beats everyone eliminated before them, winners draw with each other.
+
+Placement outcomes are scaled down by the number of opponents, so that larger games do not swing ratings further.
+*/
+type Ratings struct {
+	mu      sync.Mutex
+	k       float64
+	players map[ID]*Rating
+}
+
+// NewRatings creates a Ratings instance, with k the maximum rating change from a single outcome.
+func NewRatings(k float64) *Ratings {
+	return &Ratings{k: k, players: make(map[ID]*Rating)}
+}
+
+func (r *Ratings) rating(id ID) float64 {
+	if p, ok := r.players[id]; ok {
+		return p.Rating
+	}
+	return DefaultRating
+}
+
+// Get returns the rating of player with id.
+func (r *Ratings) Get(id ID) Rating {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.players[id]; ok {
+		return *p
+	}
+	return Rating{Rating: DefaultRating}
+}
+
+// expected score of a player rated a against a player rated b.
+func expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Update ratings from the result of a finished game. Stopped games, which nobody won, are not rated.
+func (r *Ratings) Update(res *GameResult) {
+	if res.Stopped {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// placing: players in order of elimination, winners last and level
+	var (
+		placing = make(map[ID]int)
+		delta   = make(map[ID]float64)
+	)
+	for i, k := range res.Kills {
+		placing[k.Victim.ID] = i
+	}
+	for _, p := range res.Winners {
+		placing[p.ID] = len(res.Kills)
+	}
+	var outcome = func(a, b ID, s, w float64) {
+		var e = expected(r.rating(a), r.rating(b))
+		delta[a] += r.k * w * (s - e)
+		delta[b] += r.k * w * (e - s)
+	}
+	for _, k := range res.Kills {
+		// Nb. a disqualified player is their own killer, and only loses on placing.
+		if k.Method != DisqualifiedMethod {
+			outcome(k.Killer.ID, k.Victim.ID, 1, 1)
+		}
+	}
+	if n := len(placing); n > 1 {
+		var w = 1 / float64(n-1)
+		var ids = make([]ID, 0, n)
+		for id := range placing {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i, a := range ids {
+			for _, b := range ids[i+1:] {
+				switch {
+				case placing[a] > placing[b]:
+					outcome(a, b, 1, w)
+				case placing[a] < placing[b]:
+					outcome(a, b, 0, w)
+				default:
+					outcome(a, b, 0.5, w)
+				}
+			}
+		}
+	}
+	for id := range placing {
+		var p, ok = r.players[id]
+		if !ok {
+			p = &Rating{Rating: DefaultRating}
+			r.players[id] = p
+		}
+		p.Rating += delta[id]
+		p.Games++
+	}
+}
+
+/*
+Order returns ids ordered from highest to lowest rated.
+Used as a start order (see Game.SetStartOrder), each player targets the next strongest player,
+so that every player is matched against opponents close to their own level.
+*/
+func (r *Ratings) Order(ids []ID) []ID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var o = append([]ID(nil), ids...)
+	sort.SliceStable(o, func(i, j int) bool { return r.rating(o[i]) > r.rating(o[j]) })
+	return o
+}
+
+// ratingsJSON is the JSON encoding of Ratings.
+type ratingsJSON struct {
+	K       float64       `json:"k"`
+	Players map[ID]Rating `json:"players"`
+}
+
+// MarshalJSON encodes all ratings, so they can be saved between games.
+func (r *Ratings) MarshalJSON() ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rj = ratingsJSON{r.k, make(map[ID]Rating, len(r.players))}
+	for id, p := range r.players {
+		rj.Players[id] = *p
+	}
+	return json.Marshal(rj)
+}
+
+// UnmarshalJSON restores ratings saved with MarshalJSON.
+func (r *Ratings) UnmarshalJSON(b []byte) error {
+	var rj ratingsJSON
+	if err := json.Unmarshal(b, &rj); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.k = rj.K
+	r.players = make(map[ID]*Rating, len(rj.Players))
+	for id, p := range rj.Players {
+		var c = p
+		r.players[id] = &c
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package assassin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRatings(t *testing.T) {
+	var (
+		t0      = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		a, b, c = Player{ID: 1, Name: "Ace"}, Player{ID: 2, Name: "Bee"}, Player{ID: 3, Name: "Cee"}
+		r       = NewRatings(DefaultRatingK)
+	)
+	if g := r.Get(a.ID); g.Rating != DefaultRating || g.Games != 0 {
+		t.Error("Unexpected rating for new player", g)
+	}
+	r.Update(&GameResult{
+		Winners: []Player{a},
+		Kills: []Kill{
+			{b, a, AttackMethod, t0},
+			{c, a, AssassinationMethod, t0},
+		},
+	})
+	var ra, rb, rc = r.Get(a.ID), r.Get(b.ID), r.Get(c.ID)
+	if !(ra.Rating > rc.Rating && rc.Rating > rb.Rating) {
+		t.Error("Unexpected ratings", ra, rb, rc)
+	}
+	if sum := ra.Rating + rb.Rating + rc.Rating; sum < 3*DefaultRating-1e-9 || sum > 3*DefaultRating+1e-9 {
+		t.Error("Ratings not zero-sum", sum)
+	}
+	if ra.Games != 1 || rb.Games != 1 || rc.Games != 1 {
+		t.Error("Unexpected game counts", ra, rb, rc)
+	}
+	t.Run("Stopped", func(t *testing.T) {
+		r.Update(&GameResult{Stopped: true, Winners: []Player{b, c}, Kills: []Kill{{a, b, AttackMethod, t0}}})
+		if g := r.Get(b.ID); g != rb || r.Get(a.ID) != ra {
+			t.Error("Stopped game rated", g, r.Get(a.ID))
+		}
+	})
+	t.Run("Order", func(t *testing.T) {
+		// unrated player 4 sits between winner and losers
+		if o := r.Order([]ID{2, 4, 1, 3}); o[0] != 1 || o[1] != 4 || o[2] != 3 || o[3] != 2 {
+			t.Error("Unexpected order", o)
+		}
+	})
+	t.Run("JSON", func(t *testing.T) {
+		var j, err = json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var d = NewRatings(0)
+		if err := json.Unmarshal(j, d); err != nil {
+			t.Fatal(err)
+		}
+		if d.Get(a.ID) != ra || d.k != DefaultRatingK {
+			t.Error("Unexpected decoded rating", d.Get(a.ID), d.k)
+		}
+	})
+}
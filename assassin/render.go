@@ -0,0 +1,87 @@
+package assassin
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+MessageRenderer is a Subscriber that renders game events through Lang templates,
+sending the resulting messages to a MessageHandler.
+*/
+type MessageRenderer struct {
+	tpl Lang
+	msg MessageHandler
+}
+
+// NewMessageRenderer creates a new MessageRenderer instance.
+func NewMessageRenderer(tpl Lang, msg MessageHandler) *MessageRenderer {
+	return &MessageRenderer{tpl, msg}
+}
+
+func (r *MessageRenderer) notifyStatus(p Player, t Player) {
+	var s string
+	if p.Alive {
+		if p.KillWord != "" {
+			s = r.tpl.Fmt(r.tpl.PT, t.Name, p.KillWord)
+		} else {
+			s = r.tpl.PA
+		}
+	} else {
+		s = r.tpl.PD
+	}
+	r.msg.Notify(p, s)
+}
+
+// Handle renders a single event.
+func (r *MessageRenderer) Handle(ev Event) {
+	switch ev := ev.(type) {
+	case GameStarted:
+		r.msg.Announce(r.tpl.GS)
+	case TargetAssigned:
+		r.notifyStatus(ev.Player, ev.Target)
+	case PlayerEliminated:
+		switch ev.Method {
+		case AttackMethod:
+			r.msg.Notify(ev.By, r.tpl.PAS)
+		case CounterMethod:
+			r.msg.Notify(ev.By, r.tpl.PCS)
+		case DisqualifiedMethod:
+			r.msg.Announce(r.tpl.Fmt(r.tpl.GDQ, ev.Player.Name))
+			return
+		}
+		r.msg.Announce(r.tpl.Fmt(r.tpl.GD, ev.Player.Name))
+	case CheatDetected:
+		if ev.Penalty == WarnPenalty {
+			var reason = map[Cheat]string{LongMessageCheat: r.tpl.AML, FishingCheat: r.tpl.AFK, AttackRateCheat: r.tpl.AAR}[ev.Cheat]
+			r.msg.Notify(ev.Player, r.tpl.Fmt(r.tpl.AW, reason))
+		}
+	case IntelGiven:
+		switch ev.Hint {
+		case InitialHint:
+			var initial, _ = utf8.DecodeRuneInString(ev.Contract.Name)
+			r.msg.Notify(ev.Player, r.tpl.Fmt(r.tpl.IN, string(unicode.ToUpper(initial))))
+		case SuspectsHint:
+			var s = make([]string, 0, len(ev.Suspects))
+			for _, p := range ev.Suspects {
+				s = append(s, p.Name)
+			}
+			r.msg.Notify(ev.Player, r.tpl.Fmt(r.tpl.IS, s))
+		}
+	case GameEnded:
+		if ev.Reason != "" {
+			r.msg.Announce(r.tpl.Fmt(r.tpl.GQ, ev.Reason))
+		} else {
+			r.msg.Announce(r.tpl.GE)
+		}
+		if len(ev.Winners) == 1 {
+			r.msg.Announce(r.tpl.Fmt(r.tpl.GW, ev.Winners[0].Name))
+		} else {
+			var w = make([]string, 0, len(ev.Winners))
+			for _, p := range ev.Winners {
+				w = append(w, p.Name)
+			}
+			r.msg.Announce(r.tpl.Fmt(r.tpl.GWM, w))
+		}
+	}
+}
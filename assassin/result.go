@@ -0,0 +1,31 @@
+package assassin
+
+import "time"
+
+// Kill records a single elimination in a game.
+type Kill struct {
+	Victim, Killer Player
+	Method         KillMethod
+	Time           time.Time
+}
+
+// GameResult summarises a finished game.
+type GameResult struct {
+	Game     ID
+	Winners  []Player
+	Kills    []Kill
+	Started  time.Time
+	Ended    time.Time
+	Duration time.Duration
+	Stopped  bool
+	Reason   string
+}
+
+// Eliminated returns the players killed during the game, in order of elimination.
+func (r GameResult) Eliminated() []Player {
+	var el = make([]Player, 0, len(r.Kills))
+	for _, k := range r.Kills {
+		el = append(el, k.Victim)
+	}
+	return el
+}
@@ -0,0 +1,331 @@
+package assassin
+
+import (
+	"sync"
+	"time"
+)
+
+// attack number n by p on t, countered if r, to be carried out when due.
+type attack struct {
+	n    int
+	p, t ID
+	r    bool
+	due  time.Time
+}
+
+type attackQueue struct {
+	n int
+	q []attack
+}
+
+func newAttackQueue() *attackQueue {
+	var a = new(attackQueue)
+	a.q = make([]attack, 0, 1)
+	return a
+}
+
+func (a *attackQueue) each(f func(p, t ID, r bool) bool) {
+	for i, v := range a.q {
+		a.q[i].r = f(v.p, v.t, v.r)
+	}
+}
+
+// push an attack by p on t to the queue, returning the attack number.
+func (a *attackQueue) push(p, t ID) int {
+	a.n++
+	a.q = append(a.q, attack{n: a.n, p: p, t: t})
+	return a.n
+}
+
+// setDue sets the time attack number n is to be carried out.
+func (a *attackQueue) setDue(n int, due time.Time) {
+	for i, v := range a.q {
+		if v.n == n {
+			a.q[i].due = due
+		}
+	}
+}
+
+// take attack number n from the queue.
+func (a *attackQueue) take(n int) (p, t ID, r, ok bool) {
+	for i, v := range a.q {
+		if v.n == n {
+			a.q = append(a.q[:i:i], a.q[i+1:]...)
+			return v.p, v.t, v.r, true
+		}
+	}
+	return
+}
+
+/*
+gameAction is sent to a running game to trigger an action.
+n identifies the attack for AttackAction, reason explains a QuitAction.
+*/
+type gameAction struct {
+	GameActionConst
+	n      int
+	reason string
+}
+
+// attackDue is sent to a running game once the timing of attack number n is known.
+type attackDue struct {
+	n  int
+	at time.Time
+}
+
+/*
+gameRun contains state information for a single game running on an engine.
+The game logic lives here, driven by the engine's event loop or by a Replayer.
+changed is set whenever the state saved in a snapshot changes, and cleared by the engine once it has saved.
+Its hooks are:
+
+	now returns the time to stamp events with.
+	publish sends out an event.
+	record appends an entry to the game log.
+	schedule starts the timer for attack number n.
+	save stores a snapshot of the game.
+*/
+type gameRun struct {
+	g        *Game
+	talk     chan Talk
+	action   chan gameAction
+	due      chan attackDue
+	done     chan struct{}
+	wg       sync.WaitGroup
+	attacks  *attackQueue
+	alive    int
+	reason   string
+	started  time.Time
+	kills    []Kill
+	launches map[ID][]time.Time
+	intel    int
+	changed  bool
+	now      func() time.Time
+	publish  func(ev Event)
+	record   func(l LogEntry)
+	schedule func(n int)
+	save     func()
+}
+
+func newGameRun(g *Game) *gameRun {
+	var r = new(gameRun)
+	r.g = g
+	r.talk = make(chan Talk)
+	r.action = make(chan gameAction)
+	r.due = make(chan attackDue)
+	r.done = make(chan struct{})
+	r.attacks = newAttackQueue()
+	r.launches = make(map[ID][]time.Time)
+	r.now = time.Now
+	r.publish = func(ev Event) {}
+	r.record = func(l LogEntry) {}
+	r.schedule = func(n int) {}
+	r.save = func() {}
+	return r
+}
+
+// accepts reports whether t is in-game speech for the run.
+func (r *gameRun) accepts(t Talk) bool {
+	if t.Game == 0 {
+		return len(r.g.channels) > 0 && r.g.InChannel(t.Channel)
+	}
+	return t.Game == r.g.ID && r.g.InChannel(t.Channel)
+}
+
+func (r *gameRun) info() EventInfo {
+	return EventInfo{r.g.ID, r.now()}
+}
+
+func (r *gameRun) entry(k LogKind) LogEntry {
+	return LogEntry{Game: r.g.ID, Kind: k, Time: r.now()}
+}
+
+// over reports whether the game has finished.
+func (r *gameRun) over() bool {
+	return r.alive <= 1 || r.g.settings.Assigner != nil && r.g.settings.Assigner.Over(r.g.alive())
+}
+
+// begin the game, linking players in the given order.
+func (r *gameRun) begin(order []ID) {
+	var l = r.entry(LogCreate)
+	l.Players = make(map[ID]string, len(r.g.players))
+	var pl = make([]Player, 0, len(r.g.players))
+	r.g.WithPlayers(func(p Player) {
+		l.Players[p.ID] = p.Name
+		pl = append(pl, p)
+	})
+	l.Settings = r.g.logSettings()
+	r.record(l)
+	r.started = r.now()
+	r.publish(GameStarted{r.info(), pl})
+	l = r.entry(LogStart)
+	l.Order = order
+	r.record(l)
+	r.g.startOrder(order)
+	r.g.WithPlayers(func(p Player) {
+		var t, _ = p.GetTarget()
+		r.publish(TargetAssigned{r.info(), p, t})
+	})
+	r.alive = r.g.Status()
+}
+
+func (r *gameRun) eliminated(k, by Player, m KillMethod) {
+	var i = r.info()
+	r.changed = true
+	r.kills = append(r.kills, Kill{k, by, m, i.Time})
+	r.publish(PlayerEliminated{i, k, by, m})
+	if r.g.settings.Assigner != nil {
+		for _, id := range r.g.relink(k) {
+			var p = r.g.players[id]
+			var t, _ = p.GetTarget()
+			r.publish(TargetAssigned{r.info(), *p, t})
+		}
+		r.alive--
+		return
+	}
+	if c, ok := k.GetContract(); ok {
+		var t, _ = c.GetTarget()
+		r.publish(TargetAssigned{r.info(), c, t})
+	}
+	r.alive--
+}
+
+// handleTalk analyses a chat message for KillWords.
+func (r *gameRun) handleTalk(chat Talk) {
+	var l = r.entry(LogTalk)
+	l.Talk = &chat
+	r.record(l)
+	var g = r.g
+	var p, ok = g.GetPlayer(chat.Speaker)
+	if !ok || !p.Alive {
+		return
+	}
+	/*
+		When analysing the chatter, check for an assassination first.
+		If a message includes both player's KillWord and their contract's, the assassination will take precedence over the attack/counter.
+	*/
+	if c, ok := p.GetContract(); ok && g.says(chat.Text, c.KillWord) {
+		// p assassinated
+		if k, ok := g.ResolvePlayerKill(p.ID); ok {
+			r.publish(PlayerAssassinated{r.info(), k, c})
+			r.eliminated(k, c, AssassinationMethod)
+		}
+	} else if p.KillWord != "" && g.says(chat.Text, p.KillWord) {
+		// Nb. only talk saying the player's own KillWord is checked for cheating, as only it can launch or counter an attack.
+		if cheat, ok := r.cheat(p, chat.Text); ok {
+			r.penalise(p, cheat)
+			return
+		}
+		var retaliated = false
+		r.attacks.each(func(ap, at ID, c bool) bool {
+			if at == p.ID && !c {
+				// p is retaliating
+				retaliated = true
+				r.changed = true
+				if a, ok := g.GetPlayer(ap); ok {
+					r.publish(AttackCountered{r.info(), a, p})
+				}
+				return true
+			}
+			return c
+		})
+		if !retaliated {
+			// p is attacking
+			if t, ok := p.GetTarget(); ok {
+				if ac := g.settings.AntiCheat; ac != nil && ac.MaxAttacks > 0 {
+					var now = r.now()
+					var ls = ac.recent(r.launches[p.ID], now)
+					if len(ls) >= ac.MaxAttacks {
+						r.launches[p.ID] = ls
+						r.penalise(p, AttackRateCheat)
+						return
+					}
+					r.launches[p.ID] = append(ls, now)
+				}
+				var n = r.attacks.push(p.ID, t.ID)
+				r.changed = true
+				r.publish(AttackLaunched{r.info(), p, t})
+				r.schedule(n)
+			}
+		}
+	}
+}
+
+// cheat checks text said by p against the game's AntiCheat, reporting whether and why it is flagged.
+func (r *gameRun) cheat(p Player, text string) (Cheat, bool) {
+	if r.g.settings.AntiCheat == nil {
+		return 0, false
+	}
+	return r.g.settings.AntiCheat.check(r.g, text)
+}
+
+// penalise p for cheating.
+func (r *gameRun) penalise(p Player, c Cheat) {
+	var pen = r.g.settings.AntiCheat.Penalty
+	if c == LongMessageCheat && pen > WarnPenalty {
+		// Nb. a long message may well be innocent, so is never grounds for disqualification.
+		pen = WarnPenalty
+	}
+	r.publish(CheatDetected{r.info(), p, c, pen})
+	if pen == EliminatePenalty {
+		if k, ok := r.g.ResolvePlayerKill(p.ID); ok {
+			r.eliminated(k, k, DisqualifiedMethod)
+		}
+	}
+}
+
+// handleAttack carries out attack number n once its timer has fired.
+func (r *gameRun) handleAttack(n int) {
+	var l = r.entry(LogTimer)
+	l.Attack = n
+	r.record(l)
+	var g = r.g
+	var pid, tid, c, ok = r.attacks.take(n)
+	if !ok {
+		return
+	}
+	r.changed = true
+	if c {
+		if k, ok := g.ResolvePlayerCounter(tid, pid); ok {
+			var t, _ = g.GetPlayer(tid)
+			r.eliminated(k, t, CounterMethod)
+		}
+	} else {
+		if k, ok := g.ResolvePlayerAttack(pid, tid); ok {
+			var p, _ = g.GetPlayer(pid)
+			r.eliminated(k, p, AttackMethod)
+		}
+	}
+}
+
+// quit the game early, for the given reason.
+func (r *gameRun) quit(reason string) {
+	var l = r.entry(LogQuit)
+	l.Reason = reason
+	r.record(l)
+	r.alive = 0
+	r.reason = reason
+	r.changed = true
+}
+
+// end the game, announcing the winners and returning the result.
+func (r *gameRun) end() *GameResult {
+	var w = make([]Player, 0, 1)
+	r.g.WithPlayers(func(p Player) {
+		if p.Alive {
+			w = append(w, p)
+		}
+	})
+	var ev = GameEnded{r.info(), w, r.reason}
+	r.publish(ev)
+	return &GameResult{
+		Game:     r.g.ID,
+		Winners:  w,
+		Kills:    append([]Kill(nil), r.kills...),
+		Started:  r.started,
+		Ended:    ev.Time,
+		Duration: ev.Time.Sub(r.started),
+		Stopped:  r.reason != "",
+		Reason:   r.reason,
+	}
+}
@@ -0,0 +1,48 @@
+package assassin
+
+import "testing"
+
+func TestAttackQueue(t *testing.T) {
+	var q = newAttackQueue()
+	t.Run("push", func(t *testing.T) {
+		if n := q.push(1, 2); n != 1 {
+			t.Error("Expected attack number 1, got", n)
+		}
+		q.push(3, 4)
+		if len(q.q) != 2 {
+			t.Error("Push to q failed")
+		}
+	})
+	t.Run("each", func(tt *testing.T) {
+		var c = 0
+		q.each(func(p, t ID, r bool) bool {
+			c++
+			if r {
+				tt.Error("Expected r = false for", p, t)
+			}
+			return p == 1
+		})
+		if c != 2 {
+			tt.Error("Iterated", c, "times, expected 2")
+		}
+		q.each(func(p, t ID, r bool) bool {
+			if p == 1 && !r || p != 1 && r {
+				tt.Error("Expected r =", !r, "for", p, t)
+			}
+			return r
+		})
+	})
+	t.Run("take", func(tt *testing.T) {
+		var p2, t2, r2, ok2 = q.take(2)
+		if p2 != 3 || t2 != 4 || r2 || !ok2 {
+			tt.Error("Expected 3, 4, false, true, got", p2, t2, r2, ok2)
+		}
+		var p, t, r, ok = q.take(1)
+		if p != 1 || t != 2 || !r || !ok {
+			tt.Error("Expected 1, 2, true, true, got", p, t, r, ok)
+		}
+		if _, _, _, ok := q.take(1); ok {
+			tt.Error("Expected attack 1 to be taken already")
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package assassin
+
+import (
+	"encoding/json"
+	"time"
+)
+
+/*
+Settings are the rules a game is played by, besides its players and KillWords.
+They are saved with the game and logged when it starts, so resumed and replayed games play by the same rules.
+Only the Matchers and TargetAssigners of this package are encoded; a game decoded with others keeps those it had.
+*/
+type Settings struct {
+	// Matcher decides whether talk says a KillWord. Nil for DefaultMatcher.
+	Matcher Matcher
+	// AntiCheat flags players fishing for KillWords. Nil for no checks.
+	AntiCheat *AntiCheat
+	// Intel is the quiet spell after which players are given hints. 0 for none.
+	Intel time.Duration
+	// Assigner decides who targets whom. Nil for a single chain.
+	Assigner TargetAssigner
+}
+
+// settingsJSON is the JSON encoding of Settings, with an intel spell like "10m".
+type settingsJSON struct {
+	Match     *WordMatcher `json:"match,omitempty"`
+	Exact     bool         `json:"exact,omitempty"`
+	AntiCheat *AntiCheat   `json:"antiCheat,omitempty"`
+	Intel     string       `json:"intel,omitempty"`
+	Teams     Teams        `json:"teams,omitempty"`
+}
+
+// MarshalJSON encodes the settings, leaving out any Matcher or TargetAssigner it cannot encode.
+func (s Settings) MarshalJSON() ([]byte, error) {
+	var sj = settingsJSON{AntiCheat: s.AntiCheat}
+	switch m := s.Matcher.(type) {
+	case WordMatcher:
+		sj.Match = &m
+	case *WordMatcher:
+		sj.Match = m
+	case exactMatcher:
+		sj.Exact = true
+	}
+	if s.Intel != 0 {
+		sj.Intel = s.Intel.String()
+	}
+	if ts, ok := s.Assigner.(Teams); ok {
+		sj.Teams = ts
+	}
+	return json.Marshal(sj)
+}
+
+// UnmarshalJSON decodes settings, keeping those of s not in the encoding.
+func (s *Settings) UnmarshalJSON(b []byte) error {
+	var sj settingsJSON
+	if err := json.Unmarshal(b, &sj); err != nil {
+		return err
+	}
+	switch {
+	case sj.Match != nil:
+		s.Matcher = *sj.Match
+	case sj.Exact:
+		s.Matcher = ExactMatcher
+	}
+	if sj.AntiCheat != nil {
+		s.AntiCheat = sj.AntiCheat
+	}
+	if sj.Intel != "" {
+		var err error
+		if s.Intel, err = time.ParseDuration(sj.Intel); err != nil {
+			return err
+		}
+	}
+	if sj.Teams != nil {
+		s.Assigner = sj.Teams
+	}
+	return nil
+}
+
+// logSettings returns the settings of g to log, listing the words checked for fishing, which a replay cannot look up.
+func (g Game) logSettings() *Settings {
+	var s = g.settings
+	if ac := s.AntiCheat; ac != nil && ac.MaxKillWords > 0 && ac.Words == nil {
+		if wl, ok := g.kwg.(wordLister); ok {
+			var c = *ac
+			c.Words = wl.Words()
+			s.AntiCheat = &c
+		}
+	}
+	return &s
+}
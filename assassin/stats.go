@@ -0,0 +1,185 @@
+package assassin
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// PlayerStats contains a player's statistics, accumulated over many games.
+type PlayerStats struct {
+	ID             ID
+	Name           string
+	Games          int
+	Wins           int
+	Assassinations int
+	Attacks        int
+	Counters       int
+	Deaths         int
+	Survived       time.Duration
+}
+
+// Kills returns the player's total kills, by all methods.
+func (s PlayerStats) Kills() int {
+	return s.Assassinations + s.Attacks + s.Counters
+}
+
+// AverageSurvival returns the average time the player stayed alive per game.
+func (s PlayerStats) AverageSurvival() time.Duration {
+	if s.Games == 0 {
+		return 0
+	}
+	return s.Survived / time.Duration(s.Games)
+}
+
+// StatsOrder orders players on a Leaderboard, reporting whether a ranks above b.
+type StatsOrder func(a, b PlayerStats) bool
+
+// ByWins ranks players by wins, then kills, then fewest deaths.
+func ByWins(a, b PlayerStats) bool {
+	if a.Wins != b.Wins {
+		return a.Wins > b.Wins
+	}
+	if a.Kills() != b.Kills() {
+		return a.Kills() > b.Kills()
+	}
+	return a.Deaths < b.Deaths
+}
+
+// ByKills ranks players by kills, then wins.
+func ByKills(a, b PlayerStats) bool {
+	if a.Kills() != b.Kills() {
+		return a.Kills() > b.Kills()
+	}
+	return a.Wins > b.Wins
+}
+
+// BySurvival ranks players by average survival time.
+func BySurvival(a, b PlayerStats) bool {
+	return a.AverageSurvival() > b.AverageSurvival()
+}
+
+/*
+Leaderboard keeps cumulative player statistics from finished games.
+Add games with AddResult, or subscribe the Leaderboard to a GameEngine to add each game as it ends.
+A game counts as a win for every player left at the end, such as the last team standing, unless it was stopped early.
+*/
+type Leaderboard struct {
+	mu      sync.Mutex
+	players map[ID]*PlayerStats
+	games   map[ID]*GameResult
+}
+
+// NewLeaderboard creates a Leaderboard, starting from previously saved stats.
+func NewLeaderboard(stats ...PlayerStats) *Leaderboard {
+	var l = &Leaderboard{players: make(map[ID]*PlayerStats), games: make(map[ID]*GameResult)}
+	for _, s := range stats {
+		var c = s
+		l.players[s.ID] = &c
+	}
+	return l
+}
+
+func (l *Leaderboard) player(p Player) *PlayerStats {
+	var s, ok = l.players[p.ID]
+	if !ok {
+		s = &PlayerStats{ID: p.ID}
+		l.players[p.ID] = s
+	}
+	s.Name = p.Name
+	return s
+}
+
+// AddResult adds the result of a finished game to the statistics.
+func (l *Leaderboard) AddResult(r *GameResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, k := range r.Kills {
+		var v = l.player(k.Victim)
+		v.Games++
+		v.Deaths++
+		v.Survived += k.Time.Sub(r.Started)
+		var s = l.player(k.Killer)
+		switch k.Method {
+		case AssassinationMethod:
+			s.Assassinations++
+		case AttackMethod:
+			s.Attacks++
+		case CounterMethod:
+			s.Counters++
+		}
+	}
+	for _, p := range r.Winners {
+		var s = l.player(p)
+		s.Games++
+		s.Survived += r.Ended.Sub(r.Started)
+		if !r.Stopped {
+			s.Wins++
+		}
+	}
+}
+
+// Handle builds up game results from engine events, adding each game once it ends.
+func (l *Leaderboard) Handle(ev Event) {
+	l.mu.Lock()
+	var r, ok = l.games[ev.GameID()]
+	if !ok {
+		r = &GameResult{Game: ev.GameID(), Started: ev.At()}
+		l.games[ev.GameID()] = r
+	}
+	switch ev := ev.(type) {
+	case PlayerEliminated:
+		r.Kills = append(r.Kills, Kill{ev.Player, ev.By, ev.Method, ev.Time})
+	case GameEnded:
+		r.Winners = ev.Winners
+		r.Ended = ev.Time
+		r.Duration = r.Ended.Sub(r.Started)
+		r.Reason = ev.Reason
+		r.Stopped = ev.Reason != ""
+		delete(l.games, ev.Game)
+		l.mu.Unlock()
+		l.AddResult(r)
+		return
+	}
+	l.mu.Unlock()
+}
+
+// Get returns the statistics for player with id.
+func (l *Leaderboard) Get(id ID) (PlayerStats, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.players[id]; ok {
+		return *s, true
+	}
+	return PlayerStats{}, false
+}
+
+// Stats returns the statistics for all players, ordered by ID. Pass them to NewLeaderboard to restore.
+func (l *Leaderboard) Stats() []PlayerStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var ss = make([]PlayerStats, 0, len(l.players))
+	for _, s := range l.players {
+		ss = append(ss, *s)
+	}
+	sort.Slice(ss, func(i, j int) bool { return ss[i].ID < ss[j].ID })
+	return ss
+}
+
+// Top returns the top n players, ranked by order. Use n < 0 for all players.
+func (l *Leaderboard) Top(n int, order StatsOrder) []PlayerStats {
+	var ss = l.Stats()
+	sort.SliceStable(ss, func(i, j int) bool { return order(ss[i], ss[j]) })
+	if n >= 0 && n < len(ss) {
+		ss = ss[:n]
+	}
+	return ss
+}
+
+// Announce the top n players, ranked by order, through msg.
+func (l *Leaderboard) Announce(msg MessageHandler, tpl Lang, n int, order StatsOrder) {
+	msg.Announce(tpl.LB)
+	for i, s := range l.Top(n, order) {
+		msg.Announce(tpl.Fmt(tpl.LBE, i+1, s.Name, s.Wins, s.Kills(), s.Deaths, s.AverageSurvival().Round(time.Second)))
+	}
+}
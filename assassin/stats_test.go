@@ -0,0 +1,90 @@
+package assassin
+
+import (
+	"testing"
+	"time"
+)
+
+type recordingMessageHandler struct {
+	a, n []string
+}
+
+func (h *recordingMessageHandler) Announce(s string) { h.a = append(h.a, s) }
+
+func (h *recordingMessageHandler) Notify(p Player, s string) { h.n = append(h.n, s) }
+
+func TestLeaderboard(t *testing.T) {
+	var (
+		t0      = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		a, b, c = Player{ID: 1, Name: "Ace"}, Player{ID: 2, Name: "Bee"}, Player{ID: 3, Name: "Cee"}
+		r1      = &GameResult{
+			Game:    1,
+			Winners: []Player{a},
+			Kills: []Kill{
+				{b, a, AttackMethod, t0.Add(time.Minute)},
+				{c, a, AssassinationMethod, t0.Add(3 * time.Minute)},
+			},
+			Started: t0,
+			Ended:   t0.Add(3 * time.Minute),
+		}
+		l = NewLeaderboard()
+	)
+	l.AddResult(r1)
+	t.Run("AddResult", func(t *testing.T) {
+		var s, ok = l.Get(a.ID)
+		if !ok || s.Games != 1 || s.Wins != 1 || s.Attacks != 1 || s.Assassinations != 1 || s.Kills() != 2 || s.Deaths != 0 {
+			t.Error("Unexpected stats", s, ok)
+		}
+		if s, _ := l.Get(b.ID); s.Deaths != 1 || s.AverageSurvival() != time.Minute {
+			t.Error("Unexpected stats", s)
+		}
+	})
+	t.Run("Handle", func(t *testing.T) {
+		var i = func(d time.Duration) EventInfo { return EventInfo{2, t0.Add(d)} }
+		for _, ev := range []Event{
+			GameStarted{i(0), []Player{a, b, c}},
+			PlayerEliminated{i(time.Minute), a, c, CounterMethod},
+			GameEnded{i(5 * time.Minute), []Player{b, c}, "bored"},
+		} {
+			l.Handle(ev)
+		}
+		if s, _ := l.Get(c.ID); s.Games != 2 || s.Wins != 0 || s.Counters != 1 || s.AverageSurvival() != 4*time.Minute {
+			t.Error("Unexpected stats", s)
+		}
+		if s, _ := l.Get(a.ID); s.Games != 2 || s.Deaths != 1 || s.AverageSurvival() != 2*time.Minute {
+			t.Error("Unexpected stats", s)
+		}
+	})
+	t.Run("Top", func(t *testing.T) {
+		if top := l.Top(2, ByWins); len(top) != 2 || top[0].ID != a.ID || top[1].ID != c.ID {
+			t.Error("Unexpected top by wins", top)
+		}
+		if top := l.Top(-1, BySurvival); len(top) != 3 || top[0].ID != c.ID {
+			t.Error("Unexpected top by survival", top)
+		}
+	})
+	t.Run("Announce", func(t *testing.T) {
+		var h = new(recordingMessageHandler)
+		l.Announce(h, LangEn, 1, ByKills)
+		if len(h.a) != 2 || h.a[0] != LangEn.LB || h.a[1] != LangEn.Fmt(LangEn.LBE, 1, "Ace", 1, 2, 1, 2*time.Minute) {
+			t.Error("Unexpected announcements", h.a)
+		}
+	})
+	t.Run("Teams", func(t *testing.T) {
+		// every player on the winning team wins, unless the game was stopped
+		var l = NewLeaderboard()
+		l.AddResult(&GameResult{Game: 3, Winners: []Player{b, c}, Kills: []Kill{{a, b, AttackMethod, t0.Add(time.Minute)}}, Started: t0, Ended: t0.Add(time.Hour)})
+		l.AddResult(&GameResult{Game: 4, Winners: []Player{b, c}, Started: t0, Ended: t0.Add(time.Hour), Stopped: true, Reason: "bored"})
+		for _, p := range []Player{b, c} {
+			if s, _ := l.Get(p.ID); s.Games != 2 || s.Wins != 1 {
+				t.Error("Unexpected stats", s)
+			}
+		}
+	})
+	t.Run("Restore", func(t *testing.T) {
+		var r = NewLeaderboard(l.Stats()...)
+		if s, _ := r.Get(a.ID); s.Wins != 1 || s.Kills() != 2 {
+			t.Error("Unexpected restored stats", s)
+		}
+	})
+}
@@ -0,0 +1,198 @@
+package assassin
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+AttackState contains the saved state of a pending attack.
+Due is zero if the attack timer had not been set when the snapshot was taken.
+*/
+type AttackState struct {
+	N         int
+	Attacker  ID
+	Target    ID
+	Countered bool
+	Due       time.Time
+}
+
+/*
+Snapshot contains the saved state of a running game.
+Launches are the recent times each player launched an attack, kept for the game's AntiCheat.
+*/
+type Snapshot struct {
+	Game     *Game
+	Time     time.Time
+	Started  time.Time
+	Kills    []Kill
+	Attacks  []AttackState
+	Launches map[ID][]time.Time `json:",omitempty"`
+}
+
+// snapshot takes a Snapshot of the game run.
+func (r *gameRun) snapshot() *Snapshot {
+	var s = &Snapshot{
+		Game:    r.g,
+		Time:    r.now(),
+		Started: r.started,
+		Kills:   r.kills,
+		Attacks: make([]AttackState, 0, len(r.attacks.q)),
+	}
+	if len(r.launches) > 0 {
+		s.Launches = r.launches
+	}
+	for _, a := range r.attacks.q {
+		s.Attacks = append(s.Attacks, AttackState{a.n, a.p, a.t, a.r, a.due})
+	}
+	return s
+}
+
+// restore the game run from s, using kwg to hand out future KillWords.
+func restoreGameRun(s *Snapshot, kwg WordGenerator) *gameRun {
+	var g = s.Game
+	g.SetWordGenerator(kwg)
+	var r = newGameRun(g)
+	r.started = s.Started
+	r.kills = s.Kills
+	if s.Launches != nil {
+		r.launches = s.Launches
+	}
+	for _, a := range s.Attacks {
+		r.attacks.q = append(r.attacks.q, attack{a.N, a.Attacker, a.Target, a.Countered, a.Due})
+		if a.N > r.attacks.n {
+			r.attacks.n = a.N
+		}
+	}
+	r.alive = g.Status()
+	return r
+}
+
+// ErrGameNotStored is returned when loading a game that is not in the Store.
+var ErrGameNotStored = errors.New("Game not found in store")
+
+/*
+Store interface for saving snapshots of running games.
+Snapshots refer to the live Game, so Save must encode s before returning.
+
+	Save stores s, replacing any earlier snapshot of the same game.
+	Load retrieves the snapshot for game id.
+	Delete removes the snapshot for game id.
+	List returns the IDs of all games stored.
+*/
+type Store interface {
+	Save(s *Snapshot) error
+	Load(id ID) (*Snapshot, error)
+	Delete(id ID) error
+	List() ([]ID, error)
+}
+
+/*
+FileStore is a Store keeping all snapshots in a single JSON file.
+The file is rewritten in full on each change.
+*/
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore creates a FileStore using the file at path, which is created if needed.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) read() (map[ID]*Snapshot, error) {
+	var m = make(map[ID]*Snapshot)
+	var b, err = ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
+
+func (f *FileStore) write(m map[ID]*Snapshot) error {
+	var b, err = json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so a crash mid-write leaves the old file intact
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path))
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(b); err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Save stores s.
+func (f *FileStore) Save(s *Snapshot) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m, err = f.read()
+	if err != nil {
+		return err
+	}
+	m[s.Game.ID] = s
+	return f.write(m)
+}
+
+// Load retrieves the snapshot for game id.
+func (f *FileStore) Load(id ID) (*Snapshot, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m, err = f.read()
+	if err != nil {
+		return nil, err
+	}
+	if s, ok := m[id]; ok {
+		return s, nil
+	}
+	return nil, ErrGameNotStored
+}
+
+// Delete removes the snapshot for game id.
+func (f *FileStore) Delete(id ID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m, err = f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := m[id]; !ok {
+		return nil
+	}
+	delete(m, id)
+	return f.write(m)
+}
+
+// List returns the IDs of all games stored, in ascending order.
+func (f *FileStore) List() ([]ID, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var m, err = f.read()
+	if err != nil {
+		return nil, err
+	}
+	var ids = make([]ID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
@@ -0,0 +1,196 @@
+package assassin
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "assassin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var fs = NewFileStore(filepath.Join(dir, "games.json"))
+	if ids, err := fs.List(); err != nil || len(ids) != 0 {
+		t.Error("Unexpected List response for new store", ids, err)
+	}
+	var g = NewGame(2, map[ID]string{1: "A", 2: "B"}, NewWordList([]string{"aaaa"}, nil), nil)
+	g.Start()
+	if err := fs.Save(&Snapshot{Game: g}); err != nil {
+		t.Fatal(err)
+	}
+	fs.Save(&Snapshot{Game: NewGame(1, nil, nil, nil)})
+	if ids, err := fs.List(); err != nil || len(ids) != 2 || ids[0] != 1 {
+		t.Error("Unexpected List response", ids, err)
+	}
+	if l, err := fs.Load(2); err != nil {
+		t.Error(err)
+	} else if a, ok := l.Game.GetPlayer(1); !ok || a.KillWord != "aaaa" || a.target != l.Game.players[2] {
+		t.Error("Unexpected Load response", a, ok)
+	}
+	if err := fs.Delete(2); err != nil {
+		t.Error(err)
+	}
+	if _, err := fs.Load(2); err != ErrGameNotStored {
+		t.Error("Expected", ErrGameNotStored, "got", err)
+	}
+}
+
+func TestGameEngineResume(t *testing.T) {
+	var dir, err = ioutil.TempDir("", "assassin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var (
+		fs  = NewFileStore(filepath.Join(dir, "games.json"))
+		ns  = notifyingStore{fs, make(chan ID, 100)}
+		e   = NewGameEngine(LangEn, nil, FixedTiming(time.Hour), testClock())
+		sub = newTestSubscriber()
+		g   = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3"}, nil), nil)
+		res = make(chan error)
+	)
+	e.SetStore(ns)
+	e.Subscribe(sub)
+	go func() { var _, err = e.Run(g); res <- err }()
+	sub.wait(t, 4)
+	var as = assigned(sub.events())
+	var a, b = as[1].Player, as[1].Target
+	var c = as[b.ID].Target
+	// a attacks b, with a long countdown
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: a.ID, Text: a.KillWord})
+	var s *Snapshot
+	var to = timeout(time.Second)
+	for s == nil || len(s.Attacks) == 0 || s.Attacks[0].Due.IsZero() {
+		select {
+		case <-ns.saved:
+			s, _ = fs.Load(g.ID)
+		case d := <-to:
+			t.Fatal("Attack not saved within", d)
+		}
+	}
+	// the bot restarts with a minute of the countdown left
+	var clock = testClock()
+	s.Attacks[0].Due = clock.Now().Add(time.Minute)
+	// meanwhile, the game carries on to the end
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: b.ID, Text: a.KillWord})
+	sub.wait(t, 4)
+	// a hunts c with a new KillWord
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: assigned(sub.events())[a.ID].Player.KillWord})
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	if ids, _ := fs.List(); len(ids) != 0 {
+		t.Error("Finished game still stored", ids)
+	}
+
+	var e2 = NewGameEngine(LangEn, nil, FixedTiming(time.Hour), clock)
+	var sub2 = newTestSubscriber()
+	e2.Subscribe(sub2)
+	go func() { var _, err = e2.Resume(s, NewWordList([]string{"kw4"}, nil)); res <- err }()
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	sub2.wait(t, 2)
+	var evs = sub2.events()
+	if ev, ok := evs[0].(PlayerEliminated); !ok || ev.Player.ID != b.ID || ev.By.ID != a.ID || ev.Method != AttackMethod {
+		t.Error("Unexpected event after resume", evs[0])
+	}
+	if ev, ok := evs[1].(TargetAssigned); !ok || ev.Player.ID != a.ID || ev.Target.ID != c.ID || ev.Player.KillWord != "kw4" {
+		t.Error("Unexpected event after resume", evs[1])
+	}
+	e2.IncomingTalk(Talk{Game: g.ID, Speaker: c.ID, Text: "kw4"})
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+}
+
+// notifyingStore is a Store telling the test each time a game is saved.
+type notifyingStore struct {
+	Store
+	saved chan ID
+}
+
+func (n notifyingStore) Save(s *Snapshot) error {
+	var err = n.Store.Save(s)
+	n.saved <- s.Game.ID
+	return err
+}
+
+// countingStore is a Store counting the snapshots saved.
+type countingStore struct {
+	saves int32
+}
+
+func (c *countingStore) Save(s *Snapshot) error        { atomic.AddInt32(&c.saves, 1); return nil }
+func (c *countingStore) Load(id ID) (*Snapshot, error) { return nil, ErrGameNotStored }
+func (c *countingStore) Delete(id ID) error            { return nil }
+func (c *countingStore) List() ([]ID, error)           { return nil, nil }
+
+func TestGameEngineSaves(t *testing.T) {
+	var (
+		cs  = new(countingStore)
+		ns  = notifyingStore{cs, make(chan ID, 100)}
+		e   = NewGameEngine(LangEn, nil, FixedTiming(time.Hour), testClock())
+		sub = newTestSubscriber()
+		g   = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3"}, nil), nil)
+		res = make(chan error)
+	)
+	e.SetStore(ns)
+	e.Subscribe(sub)
+	go func() { var _, err = e.Run(g); res <- err }()
+	sub.wait(t, 4)
+	var a = assigned(sub.events())[1].Player
+	// chatter changes nothing, so is not saved
+	for i := 0; i < 3; i++ {
+		e.IncomingTalk(Talk{Game: g.ID, Speaker: a.ID, Text: "hello"})
+	}
+	// launching an attack, and setting its timer, are
+	e.IncomingTalk(Talk{Game: g.ID, Speaker: a.ID, Text: a.KillWord})
+	var to = timeout(time.Second)
+	for i := 0; i < 3; i++ {
+		select {
+		case <-ns.saved:
+		case d := <-to:
+			t.Fatal("Attack not saved within", d)
+		}
+	}
+	e.Stop(g.ID, "")
+	if r := <-res; r != nil {
+		t.Fatal(r)
+	}
+	// started, launched, timed and stopped
+	if n := atomic.LoadInt32(&cs.saves); n != 4 {
+		t.Error("Saved", n, "times, expected 4")
+	}
+}
+
+func TestGameEngineResumeContext(t *testing.T) {
+	var (
+		e   = NewGameEngine(LangEn, nil, FixedTiming(time.Minute), testClock())
+		sub = newTestSubscriber()
+		g   = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3"}, nil), nil)
+		res = make(chan *GameResult)
+	)
+	g.Start()
+	e.Subscribe(sub)
+	var ctx, cancel = context.WithCancel(context.Background())
+	go func() {
+		var r, _ = e.ResumeContext(ctx, &Snapshot{Game: g}, NewWordList([]string{"kw4"}, nil))
+		res <- r
+	}()
+	cancel()
+	select {
+	case r := <-res:
+		if !r.Stopped || len(r.Winners) != 3 {
+			t.Error("Unexpected result", r)
+		}
+	case d := <-timeout(time.Second):
+		t.Error("Resumed game not stopped within", d)
+	}
+}
@@ -0,0 +1,185 @@
+package assassin
+
+import (
+	"sort"
+	"strconv"
+)
+
+/*
+TargetAssigner decides who targets whom, in place of the single circular chain games use by default.
+A player may be the target of only one other, their contract.
+*/
+type TargetAssigner interface {
+	// Assign returns the target of each player, for the players alive listed in the order dealt. Players left out have no target.
+	Assign(order []ID) map[ID]ID
+	// Valid reports whether player p may target t.
+	Valid(p, t ID) bool
+	// Over reports whether a game with only the given players alive is over.
+	Over(alive []ID) bool
+}
+
+// ChainAssigner links players into a single circular chain, each targeting the next, as games do by default.
+type ChainAssigner struct{}
+
+// Assign links each player to the next in order, and the last to the first.
+func (ChainAssigner) Assign(order []ID) map[ID]ID {
+	var ts = make(map[ID]ID, len(order))
+	for i, id := range order {
+		ts[id] = order[(i+1)%len(order)]
+	}
+	return ts
+}
+
+// Valid reports that any player may target any other.
+func (ChainAssigner) Valid(p, t ID) bool { return true }
+
+// Over reports whether there is at most one player alive.
+func (ChainAssigner) Over(alive []ID) bool { return len(alive) <= 1 }
+
+/*
+Teams is a TargetAssigner for team games, mapping each player to the name of their team.
+Every player targets a player on another team, and the game is over once only one team is left.
+A player not listed is a team of their own.
+
+Players are dealt into a chain alternating between teams. A team with more players than all the others together
+cannot all be given targets, so its last players dealt wait without a target until enough of their team are eliminated.
+*/
+type Teams map[ID]string
+
+// team returns the team of player id.
+func (ts Teams) team(id ID) string {
+	if t, ok := ts[id]; ok {
+		return t
+	}
+	return "\x00" + strconv.Itoa(int(id))
+}
+
+// Assign deals players into a chain alternating between teams, largest team first.
+func (ts Teams) Assign(order []ID) map[ID]ID {
+	var (
+		names  []string
+		groups = make(map[string][]ID)
+	)
+	for _, id := range order {
+		var t = ts.team(id)
+		if _, ok := groups[t]; !ok {
+			names = append(names, t)
+		}
+		groups[t] = append(groups[t], id)
+	}
+	var links = make(map[ID]ID)
+	if len(names) < 2 {
+		return links
+	}
+	sort.SliceStable(names, func(i, j int) bool { return len(groups[names[i]]) > len(groups[names[j]]) })
+	// Nb. the largest team can fill at most every other place in the chain.
+	if most, rest := len(groups[names[0]]), len(order)-len(groups[names[0]]); most > rest {
+		groups[names[0]] = groups[names[0]][:rest]
+	}
+	var dealt []ID
+	for _, t := range names {
+		dealt = append(dealt, groups[t]...)
+	}
+	// place players in the even places of the chain, then the odd, so no two of a team are next to each other
+	var chain = make([]ID, len(dealt))
+	var i = 0
+	for _, start := range []int{0, 1} {
+		for p := start; p < len(chain); p += 2 {
+			chain[p] = dealt[i]
+			i++
+		}
+	}
+	for i, id := range chain {
+		links[id] = chain[(i+1)%len(chain)]
+	}
+	return links
+}
+
+// Valid reports whether p and t are on different teams.
+func (ts Teams) Valid(p, t ID) bool { return ts.team(p) != ts.team(t) }
+
+// Over reports whether the players alive are all on the same team.
+func (ts Teams) Over(alive []ID) bool {
+	for _, id := range alive {
+		if ts.team(id) != ts.team(alive[0]) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetTargetAssigner sets the TargetAssigner deciding who targets whom. Use nil for a single chain, as by default.
+func (g *Game) SetTargetAssigner(a TargetAssigner) {
+	g.settings.Assigner = a
+}
+
+// alive returns the IDs of players alive, in ascending order.
+func (g Game) alive() []ID {
+	var ids []ID
+	for _, id := range g.ids() {
+		if g.players[id].Alive {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// chainOrder returns the players alive in order along their chains of targets, starting from the lowest ID.
+func (g Game) chainOrder() []ID {
+	var (
+		order []ID
+		seen  = make(map[ID]bool)
+	)
+	for _, id := range g.ids() {
+		for p := g.players[id]; p != nil && p.Alive && !seen[p.ID]; p = p.target {
+			seen[p.ID] = true
+			order = append(order, p.ID)
+		}
+	}
+	return order
+}
+
+/*
+relink deals targets again after k is eliminated, if the game's TargetAssigner is not satisfied.
+Returns the players whose target changed, in order of ID.
+*/
+func (g *Game) relink(k Player) []ID {
+	var changed = make(map[ID]bool)
+	var deal bool
+	if c, ok := k.GetContract(); ok {
+		changed[c.ID] = true
+		var t, ok = c.GetTarget()
+		deal = !ok || t.ID == c.ID || !g.settings.Assigner.Valid(c.ID, t.ID)
+	}
+	for _, id := range g.alive() {
+		deal = deal || g.players[id].target == nil
+	}
+	if deal {
+		var links = g.settings.Assigner.Assign(g.chainOrder())
+		for _, id := range g.alive() {
+			var p = g.players[id]
+			var want, ok = links[id]
+			if (p.target == nil) == ok || ok && p.target.ID != want {
+				changed[id] = true
+			}
+		}
+		// Nb. targets are all cleared before any are set, so that no new contract is unlinked.
+		for _, id := range g.ids() {
+			if changed[id] && g.players[id].Alive {
+				g.players[id].SetTarget(nil)
+			}
+		}
+		for _, id := range g.ids() {
+			if t, ok := links[id]; ok && changed[id] {
+				g.players[id].SetTarget(g.players[t])
+			}
+		}
+	}
+	var ids []ID
+	for _, id := range g.ids() {
+		if changed[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
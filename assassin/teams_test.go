@@ -0,0 +1,104 @@
+package assassin
+
+import "testing"
+
+func TestTeamsAssign(t *testing.T) {
+	var cases = []struct {
+		teams Teams
+		order []ID
+		idle  int
+	}{
+		{Teams{1: "red", 2: "red", 3: "blue", 4: "blue"}, []ID{1, 2, 3, 4}, 0},
+		{Teams{1: "a", 2: "a", 3: "a", 4: "b", 5: "b", 6: "b", 7: "c", 8: "c"}, []ID{8, 7, 6, 5, 4, 3, 2, 1}, 0},
+		{Teams{1: "a", 2: "a", 3: "b", 4: "b", 5: "c"}, []ID{5, 1, 3, 2, 4}, 0},
+		{Teams{1: "red", 2: "red", 3: "red", 4: "blue"}, []ID{1, 2, 3, 4}, 2},
+		{Teams{1: "red", 2: "red"}, []ID{1, 2, 3}, 1},
+		{Teams{1: "red", 2: "red"}, []ID{1, 2}, 2},
+	}
+	for _, c := range cases {
+		var links = c.teams.Assign(c.order)
+		var hunted = make(map[ID]bool)
+		for p, tg := range links {
+			if hunted[tg] || !c.teams.Valid(p, tg) {
+				t.Error("Invalid link", p, "->", tg, "for", c.teams)
+			}
+			hunted[tg] = true
+		}
+		if idle := len(c.order) - len(links); idle != c.idle {
+			t.Error("Idle", idle, "!=", c.idle, "for", c.teams, links)
+		}
+	}
+	if !(Teams{1: "red", 2: "red"}).Over([]ID{1, 2}) || (Teams{1: "red"}).Over([]ID{1, 2}) {
+		t.Error("Unexpected end of game")
+	}
+}
+
+func TestTeamGame(t *testing.T) {
+	var teams = Teams{1: "red", 2: "red", 3: "blue", 4: "blue", 5: "blue"}
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee", 4: "Dee", 5: "Eee"}, NewWordList([]string{"kw1", "kw2", "kw3", "kw4", "kw5", "kw6"}, nil), nil)
+	g.SetTargetAssigner(teams)
+	var r = newGameRun(g)
+	var entries []LogEntry
+	r.record = func(l LogEntry) { entries = append(entries, l) }
+	g.SetWordGenerator(&recordingWords{g.kwg, func(w string) { entries = append(entries, LogEntry{Game: 1, Kind: LogWord, Word: w}) }})
+	var assigned = make(map[ID]TargetAssigned)
+	r.publish = func(ev Event) {
+		if ev, ok := ev.(TargetAssigned); ok {
+			assigned[ev.Player.ID] = ev
+		}
+	}
+	r.begin([]ID{1, 2, 3, 4, 5})
+	// check the targets assigned so far, returning the players with one
+	var check = func() []TargetAssigned {
+		var ts []TargetAssigned
+		for _, a := range assigned {
+			if !a.Player.Alive {
+				continue
+			}
+			if a.Player.KillWord == "" {
+				continue
+			}
+			if !teams.Valid(a.Player.ID, a.Target.ID) {
+				t.Error("Invalid target", a.Player.Name, "->", a.Target.Name)
+			}
+			ts = append(ts, a)
+		}
+		return ts
+	}
+	if ts := check(); len(ts) != 4 {
+		t.Error("Expected 4 targets, got", len(ts))
+	}
+	// assassinate red players, by having their hunters' KillWords said by them
+	for _, id := range []ID{1, 2} {
+		if r.over() {
+			t.Fatal("Game over early")
+		}
+		var p, _ = g.GetPlayer(id)
+		var c, _ = p.GetContract()
+		r.handleTalk(Talk{Game: 1, Speaker: id, Text: "I say " + c.KillWord})
+		if p, _ := g.GetPlayer(id); p.Alive {
+			t.Fatal("Player", id, "not assassinated")
+		}
+		check()
+	}
+	if !r.over() {
+		t.Error("Game not over with one team left")
+	}
+	if res := r.end(); len(res.Winners) != 3 || res.Winners[0].ID != 3 {
+		t.Error("Unexpected winners", res.Winners)
+	}
+	t.Run("Replay", func(t *testing.T) {
+		var rp, err = NewReplayer(entries, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rp.Step() {
+		}
+		if _, ok := rp.Game().settings.Assigner.(Teams); !ok {
+			t.Error("Teams not replayed", rp.Game().settings)
+		}
+		if rp.Game().Status() != 3 {
+			t.Error("Replayed status", rp.Game().Status(), "!= 3")
+		}
+	})
+}
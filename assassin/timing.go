@@ -0,0 +1,246 @@
+package assassin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+/*
+Rand is the source of randomness for shuffling games and word lists, and for the random timings. A *rand.Rand will do.
+Timings are calculated concurrently, so the Rand given to a timing is only used while holding a lock.
+*/
+type Rand interface {
+	Float64() float64
+	NormFloat64() float64
+	ExpFloat64() float64
+	Perm(n int) []int
+}
+
+// globalRand is the Rand of the math/rand package's shared source.
+type globalRand struct{}
+
+func (globalRand) Float64() float64     { return rand.Float64() }
+func (globalRand) NormFloat64() float64 { return rand.NormFloat64() }
+func (globalRand) ExpFloat64() float64  { return rand.ExpFloat64() }
+func (globalRand) Perm(n int) []int     { return rand.Perm(n) }
+
+// lockedRand makes a Rand safe for concurrent use.
+type lockedRand struct {
+	mu sync.Mutex
+	r  Rand
+}
+
+func (l *lockedRand) Float64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Float64()
+}
+
+func (l *lockedRand) NormFloat64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.NormFloat64()
+}
+
+func (l *lockedRand) ExpFloat64() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.ExpFloat64()
+}
+
+func (l *lockedRand) Perm(n int) []int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Perm(n)
+}
+
+// sharedRand returns r, or the shared source if r is nil.
+func sharedRand(r Rand) Rand {
+	if r == nil {
+		return globalRand{}
+	}
+	return r
+}
+
+// newRand returns r ready for concurrent use, or the shared source if r is nil.
+func newRand(r Rand) Rand {
+	if r == nil {
+		return globalRand{}
+	}
+	return &lockedRand{r: r}
+}
+
+// clamp d to at least min, and at most max if max is set. Countdowns are never negative.
+func clamp(d, min, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		d = max
+	}
+	if d < min {
+		d = min
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+// FixedTiming gives every attack the same countdown.
+type FixedTiming time.Duration
+
+// Calc returns the fixed countdown.
+func (t FixedTiming) Calc() time.Duration { return time.Duration(t) }
+
+// UniformTiming picks countdowns uniformly at random between Min and Max, with the shared source unless made by NewUniformTiming.
+type UniformTiming struct {
+	Min, Max time.Duration
+	r        Rand
+}
+
+// NewUniformTiming creates a new UniformTiming instance, picking countdowns with r. Use nil for the shared source.
+func NewUniformTiming(min, max time.Duration, r Rand) *UniformTiming {
+	return &UniformTiming{min, max, newRand(r)}
+}
+
+// Calc picks a countdown.
+func (t *UniformTiming) Calc() time.Duration {
+	return clamp(t.Min+time.Duration(sharedRand(t.r).Float64()*float64(t.Max-t.Min)), t.Min, t.Max)
+}
+
+/*
+NormalTiming picks countdowns from a normal distribution, kept between Min and Max if set.
+Countdowns are picked with the shared source unless made by NewNormalTiming.
+*/
+type NormalTiming struct {
+	Mean, StdDev, Min, Max time.Duration
+	r                      Rand
+}
+
+// NewNormalTiming creates a new NormalTiming instance, picking countdowns with r. Use nil for the shared source.
+func NewNormalTiming(mean, stddev time.Duration, r Rand) *NormalTiming {
+	return &NormalTiming{Mean: mean, StdDev: stddev, r: newRand(r)}
+}
+
+// Calc picks a countdown.
+func (t *NormalTiming) Calc() time.Duration {
+	return clamp(t.Mean+time.Duration(sharedRand(t.r).NormFloat64()*float64(t.StdDev)), t.Min, t.Max)
+}
+
+/*
+ExponentialTiming picks countdowns from an exponential distribution, kept between Min and Max if set.
+Most attacks land quickly, but now and then one takes much longer.
+Countdowns are picked with the shared source unless made by NewExponentialTiming.
+*/
+type ExponentialTiming struct {
+	Mean, Min, Max time.Duration
+	r              Rand
+}
+
+// NewExponentialTiming creates a new ExponentialTiming instance, picking countdowns with r. Use nil for the shared source.
+func NewExponentialTiming(mean time.Duration, r Rand) *ExponentialTiming {
+	return &ExponentialTiming{Mean: mean, r: newRand(r)}
+}
+
+// Calc picks a countdown.
+func (t *ExponentialTiming) Calc() time.Duration {
+	return clamp(time.Duration(sharedRand(t.r).ExpFloat64()*float64(t.Mean)), t.Min, t.Max)
+}
+
+/*
+ScaledTiming adds PerPlayer to the countdowns of Base for each player still alive,
+so that attacks speed up as the game goes on.
+*/
+type ScaledTiming struct {
+	Base      AttackTimingFunc
+	PerPlayer time.Duration
+}
+
+// Calc returns a countdown of Base, as the number of players alive is not known.
+func (t ScaledTiming) Calc() time.Duration { return t.Base.Calc() }
+
+// CalcAlive returns a countdown of Base, scaled to the number of players alive.
+func (t ScaledTiming) CalcAlive(alive int) time.Duration {
+	return t.Base.Calc() + time.Duration(alive)*t.PerPlayer
+}
+
+// TimingConfigError is returned when a TimingConfig does not describe a valid timing.
+type TimingConfigError struct {
+	Field, Value string
+}
+
+func (e TimingConfigError) Error() string {
+	return fmt.Sprintf("Invalid timing %v %q", e.Field, e.Value)
+}
+
+/*
+TimingConfig describes an AttackTimingFunc in a config file, with durations written like "30s".
+A plain duration string decodes as a fixed timing.
+*/
+type TimingConfig struct {
+	// Kind is one of fixed, uniform, normal or exponential.
+	Kind string `json:"kind"`
+	// Delay is the countdown of a fixed timing.
+	Delay string `json:"delay,omitempty"`
+	// Min and Max bound the countdowns of the random timings. Max is required by uniform timings.
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+	// Mean is the average countdown of normal and exponential timings, StdDev the spread of normal ones.
+	Mean   string `json:"mean,omitempty"`
+	StdDev string `json:"stddev,omitempty"`
+	// PerPlayer, if set, is added to countdowns for each player still alive (see ScaledTiming).
+	PerPlayer string `json:"perPlayer,omitempty"`
+}
+
+// UnmarshalJSON decodes a TimingConfig, or a duration string as a fixed timing.
+func (c *TimingConfig) UnmarshalJSON(b []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		*c = TimingConfig{Kind: "fixed"}
+		return json.Unmarshal(b, &c.Delay)
+	}
+	type plain TimingConfig
+	return json.Unmarshal(b, (*plain)(c))
+}
+
+// Timing returns the AttackTimingFunc described, picking random countdowns with r. Use nil for the shared source.
+func (c TimingConfig) Timing(r Rand) (AttackTimingFunc, error) {
+	var ds = make(map[string]time.Duration)
+	for _, f := range []struct{ name, value string }{
+		{"delay", c.Delay}, {"min", c.Min}, {"max", c.Max}, {"mean", c.Mean}, {"stddev", c.StdDev}, {"perPlayer", c.PerPlayer},
+	} {
+		if f.value == "" {
+			continue
+		}
+		var d, err = time.ParseDuration(f.value)
+		if err != nil || d < 0 {
+			return nil, &TimingConfigError{f.name, f.value}
+		}
+		ds[f.name] = d
+	}
+	var t AttackTimingFunc
+	switch c.Kind {
+	case "fixed", "":
+		t = FixedTiming(ds["delay"])
+	case "uniform":
+		if ds["max"] < ds["min"] || c.Max == "" {
+			return nil, &TimingConfigError{"max", c.Max}
+		}
+		t = NewUniformTiming(ds["min"], ds["max"], r)
+	case "normal":
+		var n = NewNormalTiming(ds["mean"], ds["stddev"], r)
+		n.Min, n.Max = ds["min"], ds["max"]
+		t = n
+	case "exponential":
+		var x = NewExponentialTiming(ds["mean"], r)
+		x.Min, x.Max = ds["min"], ds["max"]
+		t = x
+	default:
+		return nil, &TimingConfigError{"kind", c.Kind}
+	}
+	if c.PerPlayer != "" {
+		t = ScaledTiming{t, ds["perPlayer"]}
+	}
+	return t, nil
+}
@@ -0,0 +1,155 @@
+package assassin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// testRand returns the same value from every distribution, and leaves permutations in order.
+type testRand float64
+
+func (r testRand) Float64() float64     { return float64(r) }
+func (r testRand) NormFloat64() float64 { return float64(r) }
+func (r testRand) ExpFloat64() float64  { return float64(r) }
+
+func (r testRand) Perm(n int) []int {
+	var p = make([]int, n)
+	for i := range p {
+		p[i] = i
+	}
+	return p
+}
+
+func TestTimings(t *testing.T) {
+	var cases = []struct {
+		atf AttackTimingFunc
+		d   time.Duration
+	}{
+		{FixedTiming(time.Second), time.Second},
+		{NewUniformTiming(time.Second, 3*time.Second, testRand(0.5)), 2 * time.Second},
+		{NewNormalTiming(10*time.Second, 2*time.Second, testRand(-1.5)), 7 * time.Second},
+		{NewNormalTiming(time.Second, 2*time.Second, testRand(-1)), 0},
+		{&NormalTiming{Mean: 10 * time.Second, StdDev: 2 * time.Second, Max: 11 * time.Second, r: testRand(1)}, 11 * time.Second},
+		{NewExponentialTiming(4*time.Second, testRand(0.5)), 2 * time.Second},
+		{&ExponentialTiming{Mean: 4 * time.Second, Min: 3 * time.Second, r: testRand(0.5)}, 3 * time.Second},
+		{ScaledTiming{FixedTiming(time.Second), time.Second}, time.Second},
+		// struct literals pick with the shared source
+		{&UniformTiming{Min: 2 * time.Second, Max: 2 * time.Second}, 2 * time.Second},
+		{&NormalTiming{Mean: 5 * time.Second}, 5 * time.Second},
+		{&ExponentialTiming{Mean: time.Second, Min: time.Hour, Max: time.Hour}, time.Hour},
+	}
+	for _, c := range cases {
+		if d := c.atf.Calc(); d != c.d {
+			t.Error("Timing", c.atf, "gave", d, "!=", c.d)
+		}
+	}
+	if d := (ScaledTiming{FixedTiming(time.Second), time.Second}).CalcAlive(3); d != 4*time.Second {
+		t.Error("Scaled timing gave", d)
+	}
+}
+
+func TestTimingConfig(t *testing.T) {
+	t.Run("Parse", func(t *testing.T) {
+		var cases = []struct {
+			s string
+			d time.Duration
+		}{
+			{`"30s"`, 30 * time.Second},
+			{`{"kind": "fixed", "delay": "1m"}`, time.Minute},
+			{`{"kind": "uniform", "min": "10s", "max": "20s"}`, 15 * time.Second},
+			{`{"kind": "normal", "mean": "10s", "stddev": "2s", "max": "10s"}`, 10 * time.Second},
+			{`{"kind": "exponential", "mean": "10s"}`, 5 * time.Second},
+			{`{"kind": "fixed", "delay": "10s", "perPlayer": "5s"}`, 10 * time.Second},
+		}
+		for _, c := range cases {
+			var tc TimingConfig
+			if err := json.Unmarshal([]byte(c.s), &tc); err != nil {
+				t.Error("Config", c.s, err)
+				continue
+			}
+			var atf, err = tc.Timing(testRand(0.5))
+			if err != nil {
+				t.Error("Config", c.s, err)
+			} else if d := atf.Calc(); d != c.d {
+				t.Error("Config", c.s, "gave", d, "!=", c.d)
+			}
+		}
+		var tc TimingConfig
+		json.Unmarshal([]byte(`{"kind": "fixed", "delay": "10s", "perPlayer": "5s"}`), &tc)
+		var atf, _ = tc.Timing(nil)
+		if a, ok := atf.(AliveTimingFunc); !ok || a.CalcAlive(2) != 20*time.Second {
+			t.Error("Unexpected scaled timing", atf)
+		}
+	})
+	t.Run("Invalid", func(t *testing.T) {
+		var cases = []struct {
+			tc    TimingConfig
+			field string
+		}{
+			{TimingConfig{Kind: "sometimes"}, "kind"},
+			{TimingConfig{Kind: "fixed", Delay: "soon"}, "delay"},
+			{TimingConfig{Kind: "fixed", Delay: "-1s"}, "delay"},
+			{TimingConfig{Kind: "uniform", Min: "10s"}, "max"},
+			{TimingConfig{Kind: "uniform", Min: "10s", Max: "5s"}, "max"},
+			{TimingConfig{Kind: "normal", Mean: "10s", PerPlayer: "x"}, "perPlayer"},
+		}
+		for _, c := range cases {
+			var _, err = c.tc.Timing(nil)
+			if e, ok := err.(*TimingConfigError); !ok || e.Field != c.field {
+				t.Error("Config", c.tc, "gave", err)
+			}
+		}
+	})
+}
+
+// aliveTiming records the number of players alive at each attack.
+type aliveTiming chan int
+
+func (a aliveTiming) Calc() time.Duration { return time.Hour }
+
+func (a aliveTiming) CalcAlive(alive int) time.Duration {
+	a <- alive
+	return time.Hour
+}
+
+// targetSubscriber passes on target assignments.
+type targetSubscriber chan TargetAssigned
+
+func (s targetSubscriber) Handle(ev Event) {
+	if ev, ok := ev.(TargetAssigned); ok {
+		s <- ev
+	}
+}
+
+func TestAliveTiming(t *testing.T) {
+	var at = make(aliveTiming, 1)
+	var ts = make(targetSubscriber, 8)
+	var e = NewGameEngine(LangEn, nil, at, nil)
+	e.Subscribe(ts)
+	var g = NewGame(1, map[ID]string{1: "Ace", 2: "Bee", 3: "Cee"}, NewWordList([]string{"kw1", "kw2", "kw3"}, nil), nil)
+	var res = make(chan error)
+	go func() {
+		var _, err = e.Run(g)
+		res <- err
+	}()
+	var ta TargetAssigned
+	select {
+	case ta = <-ts:
+	case <-time.After(time.Second):
+		t.Fatal("No target assigned")
+	}
+	e.IncomingTalk(Talk{Game: 1, Speaker: ta.Player.ID, Name: ta.Player.Name, Time: time.Now(), Text: "I said " + ta.Player.KillWord})
+	select {
+	case n := <-at:
+		if n != 3 {
+			t.Error("Alive", n, "!= 3")
+		}
+	case <-time.After(time.Second):
+		t.Error("Timing not calculated")
+	}
+	e.Stop(1, "done")
+	if err := <-res; err != nil {
+		t.Error(err)
+	}
+}
@@ -3,7 +3,6 @@ package assassin
 import (
 	"bufio"
 	"io"
-	"math/rand"
 )
 
 /*
@@ -23,10 +22,10 @@ type WordList struct {
 	current int
 }
 
-// NewWordList creates a WordList from a given words.
-func NewWordList(words []string) *WordList {
+// NewWordList creates a WordList from a given words, shuffled with r. Use nil for the shared source.
+func NewWordList(words []string, r Rand) *WordList {
 	var g = &WordList{make([]string, 0), 0}
-	for _, i := range rand.Perm(len(words)) {
+	for _, i := range sharedRand(r).Perm(len(words)) {
 		g.words = append(g.words, words[i])
 	}
 	return g
@@ -34,9 +33,9 @@ func NewWordList(words []string) *WordList {
 
 /*
 WordListFromReader creates a WordList, reading the
-contents of the provided r to construct the word list.
+contents of the provided r to construct the word list, shuffled with rnd.
 */
-func WordListFromReader(r io.Reader) (*WordList, error) {
+func WordListFromReader(r io.Reader, rnd Rand) (*WordList, error) {
 	var (
 		s = bufio.NewScanner(r)
 		w = make([]string, 0)
@@ -45,7 +44,12 @@ func WordListFromReader(r io.Reader) (*WordList, error) {
 	for s.Scan() {
 		w = append(w, s.Text())
 	}
-	return NewWordList(w), s.Err()
+	return NewWordList(w, rnd), s.Err()
+}
+
+// Words returns all the words in the list.
+func (g *WordList) Words() []string {
+	return append([]string(nil), g.words...)
 }
 
 // Next picks the next word from the list.
@@ -11,8 +11,7 @@ func TestWordList(t *testing.T) {
 		wl  = strings.NewReader("three\n   one   two")
 		exp = []string{"one", "two", "three", "one"}
 	)
-	rand.Seed(0)
-	var g, err = WordListFromReader(wl)
+	var g, err = WordListFromReader(wl, rand.New(rand.NewSource(0)))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,219 @@
+/*
+Package chat joins chat networks to assassin games.
+
+A network only needs to implement Transport, carrying messages to and from a single room.
+A Bridge then does the engine wiring: it is the assassin.MessageHandler for the room,
+and passes room messages on as assassin.Talk, with each network user mapped to a player ID.
+Talk is queued and passed on in order from the Bridge's own goroutine,
+so a Transport's receiving loop never waits on the engine, which may itself be waiting to send.
+Networks with an HTTP API can use Retry to wait out rate limits.
+
+Usage:
+
+	var b = chat.NewBridge(transport, nil)
+	var cmd = assassin.NewCommands(engine, assassin.LangEn, words)
+	b.SetTalkHandler(func(t assassin.Talk) { cmd.Handle(t, b) })
+	go b.Run(ctx)
+*/
+package chat
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// Message is a message said in a room.
+type Message struct {
+	// Room identifies the room on its network.
+	Room string
+	// User identifies the speaker on the network, Name is how they are shown.
+	User, Name string
+	// Account, if known, is the speaker's authenticated account, which no one else can take.
+	Account string
+	Time    time.Time
+	Text    string
+}
+
+// Transport is a connection to a chat network, carrying messages to and from a single room.
+type Transport interface {
+	// Run connects to the network and passes messages said in the room to recv, until ctx is done.
+	Run(ctx context.Context, recv func(m Message)) error
+	// Send text to the room.
+	Send(text string) error
+	// SendPrivate sends text privately to user.
+	SendPrivate(user, text string) error
+}
+
+// Bridge connects a Transport to the game engine.
+type Bridge struct {
+	t     Transport
+	mu    sync.Mutex
+	talk  func(t assassin.Talk)
+	ids   map[string]assassin.ID
+	users map[assassin.ID]string
+	net   string
+	queue []assassin.Talk
+	wake  chan struct{}
+}
+
+/*
+NewBridge creates a new Bridge instance over transport t.
+Talk in the room is passed to talk, typically GameEngine.IncomingTalk or a Commands.Handle wrapper.
+*/
+func NewBridge(t Transport, talk func(t assassin.Talk)) *Bridge {
+	if talk == nil {
+		talk = func(t assassin.Talk) {}
+	}
+	return &Bridge{
+		t:     t,
+		talk:  talk,
+		ids:   make(map[string]assassin.ID),
+		users: make(map[assassin.ID]string),
+		net:   fmt.Sprintf("%T", t),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// SetTalkHandler sets the function that talk in the room is passed to.
+func (b *Bridge) SetTalkHandler(talk func(t assassin.Talk)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.talk = talk
+}
+
+/*
+ID returns the player ID for network user, hashed from the user and the kind of Transport,
+so the same user has the same ID after a restart and in every Bridge over the same network.
+In the unlikely event that two users hash alike, the one seen later takes the next free ID.
+*/
+func (b *Bridge) ID(user string) assassin.ID {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if id, ok := b.ids[user]; ok {
+		return id
+	}
+	var h = fnv.New32a()
+	fmt.Fprint(h, b.net, "\x00", user)
+	// Nb. IDs are kept positive, whatever the size of int.
+	var id = assassin.ID(h.Sum32() >> 1)
+	for _, ok := b.users[id]; ok || id == 0; _, ok = b.users[id] {
+		id++
+	}
+	b.ids[user] = id
+	b.users[id] = user
+	return id
+}
+
+// User returns the network user of the player with id.
+func (b *Bridge) User(id assassin.ID) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var u, ok = b.users[id]
+	return u, ok
+}
+
+// Run the transport until ctx is done. Talk already received is passed on before Run returns.
+func (b *Bridge) Run(ctx context.Context) error {
+	var stop, done = make(chan struct{}), make(chan struct{})
+	go b.dispatch(stop, done)
+	var err = b.t.Run(ctx, b.recv)
+	close(stop)
+	<-done
+	return err
+}
+
+// dispatch passes queued talk on in order, until stop is closed and the queue is empty.
+func (b *Bridge) dispatch(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	for {
+		var stopped bool
+		select {
+		case <-b.wake:
+		case <-stop:
+			stopped = true
+		}
+		b.mu.Lock()
+		var q, talk = b.queue, b.talk
+		b.queue = nil
+		b.mu.Unlock()
+		for _, t := range q {
+			talk(t)
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+func (b *Bridge) recv(m Message) {
+	var t = assassin.Talk{
+		Channel: m.Room,
+		Speaker: b.ID(m.User),
+		Name:    m.Name,
+		Account: m.Account,
+		Time:    m.Time,
+		Text:    m.Text,
+	}
+	if t.Name == "" {
+		t.Name = m.User
+	}
+	if t.Time.IsZero() {
+		t.Time = time.Now()
+	}
+	b.mu.Lock()
+	b.queue = append(b.queue, t)
+	b.mu.Unlock()
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Announce sends s to the room.
+func (b *Bridge) Announce(s string) {
+	// Nb. a failure to send does not stop the game.
+	b.t.Send(s)
+}
+
+// Notify sends s privately to p. Players the Bridge has not seen in the room cannot be notified.
+func (b *Bridge) Notify(p assassin.Player, s string) {
+	if u, ok := b.User(p.ID); ok {
+		b.t.SendPrivate(u, s)
+	}
+}
+
+// maxRetries is how many times a rate limited request is retried.
+const maxRetries = 3
+
+/*
+Retry sends the request made by req with client, returning the response and its body, already read and closed.
+Rate limited requests are retried after the delay returned by wait for the response, up to 3 times.
+*/
+func Retry(client *http.Client, req func() (*http.Request, error), wait func(resp *http.Response, body []byte) time.Duration) (*http.Response, []byte, error) {
+	for try := 0; ; try++ {
+		var r, err = req()
+		if err != nil {
+			return nil, nil, err
+		}
+		resp, err := client.Do(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || try == maxRetries {
+			return resp, body, nil
+		}
+		time.Sleep(wait(resp, body))
+	}
+}
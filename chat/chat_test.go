@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// testTransport replays a fixed list of messages, recording what is sent.
+type testTransport struct {
+	in   []Message
+	room []string
+	priv map[string][]string
+	read chan struct{}
+}
+
+func (t *testTransport) Run(ctx context.Context, recv func(m Message)) error {
+	for _, m := range t.in {
+		recv(m)
+	}
+	if t.read != nil {
+		close(t.read)
+	}
+	return nil
+}
+
+func (t *testTransport) Send(text string) error {
+	t.room = append(t.room, text)
+	return nil
+}
+
+func (t *testTransport) SendPrivate(user, text string) error {
+	t.priv[user] = append(t.priv[user], text)
+	return nil
+}
+
+func TestBridge(t *testing.T) {
+	var tr = &testTransport{
+		in: []Message{
+			{Room: "room", User: "@ace:example.org", Name: "Ace", Text: "hello"},
+			{Room: "room", User: "@bee:example.org", Text: "hi"},
+			{Room: "room", User: "@ace:example.org", Name: "Ace", Text: "again"},
+		},
+		priv: make(map[string][]string),
+	}
+	var talk []assassin.Talk
+	var b = NewBridge(tr, func(t assassin.Talk) { talk = append(talk, t) })
+	b.Run(context.Background())
+	if len(talk) != 3 {
+		t.Fatal("Unexpected talk", talk)
+	}
+	if a := talk[0]; a.Speaker != b.ID("@ace:example.org") || a.Name != "Ace" || a.Channel != "room" || a.Text != "hello" || a.Time.IsZero() {
+		t.Error("Unexpected talk", a)
+	}
+	if talk[1].Name != "@bee:example.org" || talk[1].Speaker == talk[0].Speaker || talk[2].Speaker != talk[0].Speaker {
+		t.Error("Unexpected speakers", talk)
+	}
+	b.Announce("The game has begun.")
+	b.Notify(assassin.Player{ID: talk[1].Speaker}, "secret")
+	b.Notify(assassin.Player{ID: 99}, "lost")
+	if len(tr.room) != 1 || len(tr.priv) != 1 || tr.priv["@bee:example.org"][0] != "secret" {
+		t.Error("Unexpected messages", tr.room, tr.priv)
+	}
+}
+
+func TestBridgeQueue(t *testing.T) {
+	var tr = &testTransport{
+		in:   []Message{{User: "ace", Text: "one"}, {User: "ace", Text: "two"}},
+		read: make(chan struct{}),
+	}
+	// talk blocks, as the engine does while waiting to send
+	var release = make(chan struct{})
+	var talk []string
+	var b = NewBridge(tr, func(t assassin.Talk) {
+		<-release
+		talk = append(talk, t.Text)
+	})
+	var res = make(chan error)
+	go func() { res <- b.Run(context.Background()) }()
+	select {
+	case <-tr.read:
+	case <-time.After(time.Second):
+		t.Fatal("Transport blocked by talk")
+	}
+	close(release)
+	<-res
+	if len(talk) != 2 || talk[0] != "one" || talk[1] != "two" {
+		t.Error("Unexpected talk", talk)
+	}
+}
+
+func TestBridgeID(t *testing.T) {
+	var b1, b2 = NewBridge(&testTransport{}, nil), NewBridge(&testTransport{}, nil)
+	// users seen in a different order, as after a restart
+	var ace, bee = b1.ID("ace"), b1.ID("bee")
+	if b2.ID("bee") != bee || b2.ID("ace") != ace || ace == bee || ace <= 0 || bee <= 0 {
+		t.Error("Unstable IDs", ace, bee, b2.ID("ace"), b2.ID("bee"))
+	}
+	if u, ok := b2.User(ace); !ok || u != "ace" {
+		t.Error("Unexpected user", u, ok)
+	}
+	// another network has its own IDs
+	if b3 := NewBridge(&otherTransport{}, nil); b3.ID("ace") == ace {
+		t.Error("IDs shared across networks", ace)
+	}
+	// a user hashing like one already seen takes another ID
+	var b4 = NewBridge(&testTransport{}, nil)
+	b4.users[ace] = "eee"
+	if id := b4.ID("ace"); id == ace || id <= 0 {
+		t.Error("Colliding ID", id)
+	}
+}
+
+// otherTransport is a Transport of another network.
+type otherTransport struct{ testTransport }
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// console is the MessageHandler of the console transport, writing messages to w.
+type console struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (c *console) printf(format string, a ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(c.w, format, a...)
+}
+
+// Announce prints s.
+func (c *console) Announce(s string) {
+	c.printf("%v\n", s)
+}
+
+// Notify prints s, marked as private to p.
+func (c *console) Notify(p assassin.Player, s string) {
+	c.printf("(to %v) %v\n", p.Name, s)
+}
+
+// consoleGame renders the events of a console game, noting when it has begun.
+type consoleGame struct {
+	r       *assassin.MessageRenderer
+	started chan struct{}
+}
+
+func (g *consoleGame) Handle(ev assassin.Event) {
+	if _, ok := ev.(assassin.GameStarted); ok {
+		close(g.started)
+	}
+	g.r.Handle(ev)
+}
+
+// parseLine splits a line of console input like "alice: hello" into the speaker and what they said.
+func parseLine(line string) (name, text string, ok bool) {
+	var i = strings.IndexByte(line, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	name, text = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	return name, text, name != ""
+}
+
+/*
+console plays on in and out, until ctx is done or in ends.
+With players configured, a game is started straight away, and the session ends with it.
+Otherwise players sign up and start games with chat commands.
+*/
+func (b *bot) console(ctx context.Context, e *assassin.GameEngine, cmd *assassin.Commands, in io.Reader, out io.Writer) error {
+	var con = &console{w: out}
+	var ids = make(map[string]assassin.ID)
+	var id = func(name string) assassin.ID {
+		if _, ok := ids[name]; !ok {
+			ids[name] = assassin.ID(len(ids) + 1)
+		}
+		return ids[name]
+	}
+	var (
+		handle func(t assassin.Talk)
+		res    = make(chan error, 1)
+	)
+	if len(b.cfg.Players) > 0 {
+		var pl = make(map[assassin.ID]string, len(b.cfg.Players))
+		for _, n := range b.cfg.Players {
+			pl[id(n)] = n
+		}
+		var cg = &consoleGame{assassin.NewMessageRenderer(b.tpl, con), make(chan struct{})}
+		e.Subscribe(assassin.ForGame(1, cg))
+		go func() {
+			var _, err = e.RunContext(ctx, b.newGame(pl))
+			res <- err
+		}()
+		select {
+		case <-cg.started:
+		case err := <-res:
+			return err
+		}
+		handle = func(t assassin.Talk) {
+			if _, ok := pl[t.Speaker]; !ok {
+				con.printf("%v is not playing.\n", t.Name)
+				return
+			}
+			t.Game = 1
+			e.IncomingTalk(t)
+		}
+	} else {
+		handle = func(t assassin.Talk) { cmd.Handle(t, con) }
+	}
+	var lines = make(chan string)
+	go func() {
+		defer close(lines)
+		var s = bufio.NewScanner(in)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+	}()
+	for {
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				if len(b.cfg.Players) == 0 {
+					return nil
+				}
+				e.Stop(1, "")
+				return <-res
+			}
+			var name, text, valid = parseLine(l)
+			if !valid {
+				con.printf("Say something as \"name: text\".\n")
+				continue
+			}
+			// Nb. whoever is at the console can say anything, so names stand in for accounts.
+			handle(assassin.Talk{Channel: b.cfg.Channel, Speaker: id(name), Name: name, Account: name, Time: time.Now(), Text: text})
+		case err := <-res:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,314 @@
+/*
+Command assassinbot runs assassin games on a chat network.
+
+Players sign up and start games in the game channel with chat commands (see assassin.Commands).
+Settings are read from a JSON config file:
+
+	{
+		"transport": "irc",
+		"channel": "#assassins",
+		"words": "words.txt",
+		"timing": "30s",
+		"lang": "en",
+		"admins": ["alice"],
+		"irc": {"addr": "irc.example.org:6697", "tls": true, "nick": "assassinbot"}
+	}
+
+Transport is one of console, tui, irc, slack, discord, matrix, xmpp or http, each with its own settings section.
+The console transport plays a local game on stdin and stdout, for play-testing:
+each line of input like "alice: hello" is said by the player before the colon.
+The tui transport plays a game between the configured players on a shared terminal (see package tui).
+
+Usage:
+
+	assassinbot [-config assassinbot.json] [-console | -tui] [-players alice,bob,carol]
+*/
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/chat"
+	"github.com/joshringer/assassinbot/discord"
+	"github.com/joshringer/assassinbot/irc"
+	"github.com/joshringer/assassinbot/matrix"
+	"github.com/joshringer/assassinbot/server"
+	"github.com/joshringer/assassinbot/slack"
+	"github.com/joshringer/assassinbot/xmpp"
+)
+
+// Config is the bot's config file.
+type Config struct {
+	// Transport is the chat network to play on.
+	Transport string `json:"transport"`
+	// Channel is the game channel, or room, on the network.
+	Channel string `json:"channel"`
+	// Words is the path of the KillWord list, a file of whitespace separated words.
+	Words string `json:"words"`
+	// Timing is how long the target of an attack has to counter it, as a duration like "30s",
+	// or a random timing like {"kind": "uniform", "min": "10s", "max": "1m"} (see assassin.TimingConfig).
+	Timing assassin.TimingConfig `json:"timing"`
+	// Match sets how KillWords are matched, as whole words ignoring case and accents, and optionally stems and look-alikes.
+	Match assassin.WordMatcher `json:"match"`
+	// AntiCheat, if set, flags players fishing for KillWords, like {"maxWords": 30, "maxAttacks": 3, "window": "1m", "penalty": "warn"}.
+	AntiCheat *assassin.AntiCheat `json:"antiCheat"`
+	// Intel, if set, is how long without an elimination before players get hints about who is hunting them, like "10m".
+	Intel string `json:"intel"`
+	// Lang is the language of game messages, a key of assassin.Langs.
+	Lang string `json:"lang"`
+	// Prefix marks chat commands, "!" by default.
+	Prefix string `json:"prefix"`
+	// Admins are the accounts of the players allowed to start and stop games. Everyone is, if empty.
+	// Accounts are Slack, Discord or Matrix user IDs, IRC services accounts, XMPP JIDs, or names on the console,
+	// never display names, which anyone can take.
+	Admins []string `json:"admins"`
+	// Players are signed up to a game started straight away, on the console and tui transports.
+	Players []string `json:"players"`
+
+	IRC struct {
+		Addr     string `json:"addr"`
+		TLS      bool   `json:"tls"`
+		Password string `json:"password"`
+		Nick     string `json:"nick"`
+	} `json:"irc"`
+	Slack struct {
+		Token         string `json:"token"`
+		SigningSecret string `json:"signingSecret"`
+		Listen        string `json:"listen"`
+	} `json:"slack"`
+	Discord struct {
+		Token string `json:"token"`
+		// IgnoreEdits stops edited messages from counting as talk, instead of the words they add.
+		IgnoreEdits bool `json:"ignoreEdits"`
+	} `json:"discord"`
+	Matrix struct {
+		Homeserver string `json:"homeserver"`
+		Token      string `json:"token"`
+	} `json:"matrix"`
+	XMPP struct {
+		JID      string `json:"jid"`
+		Password string `json:"password"`
+		Addr     string `json:"addr"`
+		Nick     string `json:"nick"`
+	} `json:"xmpp"`
+	HTTP struct {
+		Listen string `json:"listen"`
+		Reveal bool   `json:"reveal"`
+	} `json:"http"`
+}
+
+// DefaultConfig contains the default settings for Config.
+var DefaultConfig = Config{
+	Transport: "console",
+	Channel:   "console",
+	Timing:    assassin.TimingConfig{Kind: "fixed", Delay: "30s"},
+	Lang:      "en",
+}
+
+// bot is everything needed to run games, as set up from a Config.
+type bot struct {
+	cfg    Config
+	tpl    assassin.Lang
+	timing assassin.AttackTimingFunc
+	intel  time.Duration
+	words  []byte
+}
+
+// readConfig reads the config file at path over the defaults. An empty path reads no file.
+func readConfig(path string) (Config, error) {
+	var cfg = DefaultConfig
+	if path == "" {
+		return cfg, nil
+	}
+	var b, err = os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, json.Unmarshal(b, &cfg)
+}
+
+// newBot checks cfg, loading the word list.
+func newBot(cfg Config) (*bot, error) {
+	var b = &bot{cfg: cfg}
+	var ok bool
+	if b.tpl, ok = assassin.Langs[cfg.Lang]; !ok {
+		return nil, fmt.Errorf("unknown lang %q", cfg.Lang)
+	}
+	var err error
+	if b.timing, err = cfg.Timing.Timing(nil); err != nil {
+		return nil, err
+	}
+	if cfg.Intel != "" {
+		if b.intel, err = time.ParseDuration(cfg.Intel); err != nil {
+			return nil, fmt.Errorf("intel: %v", err)
+		}
+	}
+	if cfg.Words == "" {
+		return nil, errors.New("no word list given")
+	}
+	if b.words, err = os.ReadFile(cfg.Words); err != nil {
+		return nil, err
+	}
+	if len(bytes.Fields(b.words)) == 0 {
+		return nil, fmt.Errorf("no words in %v", cfg.Words)
+	}
+	return b, nil
+}
+
+// newWords returns a fresh WordGenerator over the word list.
+func (b *bot) newWords() assassin.WordGenerator {
+	// Nb. the list was read successfully once, so cannot fail now.
+	var w, _ = assassin.WordListFromReader(bytes.NewReader(b.words), nil)
+	return w
+}
+
+// newGame returns game 1 between the players named, matching KillWords as configured.
+func (b *bot) newGame(players map[assassin.ID]string) *assassin.Game {
+	var g = assassin.NewGame(1, players, b.newWords(), nil)
+	g.SetMatcher(b.cfg.Match)
+	g.SetAntiCheat(b.cfg.AntiCheat)
+	g.SetIntel(b.intel)
+	return g
+}
+
+// commands returns the chat commands for engine e.
+func (b *bot) commands(e *assassin.GameEngine) *assassin.Commands {
+	var cmd = assassin.NewCommands(e, b.tpl, b.newWords)
+	cmd.SetMatcher(b.cfg.Match)
+	cmd.SetAntiCheat(b.cfg.AntiCheat)
+	cmd.SetIntel(b.intel)
+	if b.cfg.Prefix != "" {
+		cmd.SetPrefix(b.cfg.Prefix)
+	}
+	if len(b.cfg.Admins) > 0 {
+		var admins = make(map[string]bool, len(b.cfg.Admins))
+		for _, a := range b.cfg.Admins {
+			admins[a] = true
+		}
+		cmd.SetAdmin(func(t assassin.Talk) bool { return t.Account != "" && admins[t.Account] })
+	}
+	return cmd
+}
+
+// run the bot on its transport until ctx is done.
+func (b *bot) run(ctx context.Context) error {
+	var cfg = b.cfg
+	var e = assassin.NewGameEngine(b.tpl, nil, b.timing, nil)
+	var cmd = b.commands(e)
+	switch cfg.Transport {
+	case "console":
+		return b.console(ctx, e, cmd, os.Stdin, os.Stdout)
+	case "tui":
+		return b.hotseat(ctx)
+	case "irc":
+		var ic, err = b.ircConfig()
+		if err != nil {
+			return err
+		}
+		return bridge(ctx, irc.NewClient(ic), cmd)
+	case "slack":
+		var listen = cfg.Slack.Listen
+		if listen == "" {
+			listen = ":8080"
+		}
+		return bridge(ctx, slack.NewBot(slack.Config{
+			Token: cfg.Slack.Token, SigningSecret: cfg.Slack.SigningSecret, Channel: cfg.Channel, Listen: listen,
+		}), cmd)
+	case "discord":
+		return bridge(ctx, discord.NewBot(discord.Config{Token: cfg.Discord.Token, Channel: cfg.Channel, IgnoreEdits: cfg.Discord.IgnoreEdits}), cmd)
+	case "matrix":
+		return bridge(ctx, matrix.New(matrix.Config{Homeserver: cfg.Matrix.Homeserver, Token: cfg.Matrix.Token, Room: cfg.Channel}), cmd)
+	case "xmpp":
+		return bridge(ctx, xmpp.New(xmpp.Config{
+			JID: cfg.XMPP.JID, Password: cfg.XMPP.Password, Addr: cfg.XMPP.Addr, Room: cfg.Channel, Nick: cfg.XMPP.Nick,
+		}), cmd)
+	case "http":
+		var s = server.NewServer(e, b.tpl)
+		s.SetReveal(cfg.HTTP.Reveal)
+		return serve(ctx, cfg.HTTP.Listen, s)
+	}
+	return fmt.Errorf("unknown transport %q", cfg.Transport)
+}
+
+// ircConfig returns the settings of the IRC client, verifying TLS connections against the server's host name.
+func (b *bot) ircConfig() (irc.Config, error) {
+	var cfg = b.cfg
+	var ic = irc.Config{Addr: cfg.IRC.Addr, Password: cfg.IRC.Password, Nick: cfg.IRC.Nick, Channel: cfg.Channel}
+	if cfg.IRC.TLS {
+		var host, _, err = net.SplitHostPort(cfg.IRC.Addr)
+		if err != nil {
+			return ic, fmt.Errorf("irc: %v", err)
+		}
+		ic.TLS = &tls.Config{ServerName: host}
+	}
+	return ic, nil
+}
+
+// bridge runs chat commands on transport t.
+func bridge(ctx context.Context, t chat.Transport, cmd *assassin.Commands) error {
+	var b = chat.NewBridge(t, nil)
+	b.SetTalkHandler(func(t assassin.Talk) { cmd.Handle(t, b) })
+	return b.Run(ctx)
+}
+
+// serve h on addr until ctx is done.
+func serve(ctx context.Context, addr string, h http.Handler) error {
+	if addr == "" {
+		addr = ":8080"
+	}
+	var srv = &http.Server{Addr: addr, Handler: h}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return ctx.Err()
+}
+
+func main() {
+	var (
+		path    = flag.String("config", "", "path of the JSON config file")
+		console = flag.Bool("console", false, "play on the console, whatever the configured transport")
+		hotseat = flag.Bool("tui", false, "play a hot-seat game on the terminal, whatever the configured transport")
+		players = flag.String("players", "", "comma separated players of a console or tui game started straight away")
+	)
+	flag.Parse()
+	var cfg, err = readConfig(*path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *console {
+		cfg.Transport, cfg.Channel = "console", DefaultConfig.Channel
+	}
+	if *hotseat {
+		cfg.Transport = "tui"
+	}
+	if *players != "" {
+		cfg.Players = strings.Split(*players, ",")
+	}
+	b, err := newBot(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var ctx, stop = signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := b.run(ctx); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
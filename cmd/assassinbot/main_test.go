@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// testBot returns a bot over a word list of words.
+func testBot(t *testing.T, words string, players ...string) *bot {
+	var path = filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(path, []byte(words), 0644)
+	var cfg = DefaultConfig
+	cfg.Words, cfg.Players = path, players
+	var b, err = newBot(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestConfig(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "assassinbot.json")
+	os.WriteFile(path, []byte(`{"transport": "irc", "channel": "#assassins", "timing": "1m", "irc": {"nick": "bot"}, "discord": {"ignoreEdits": true}}`), 0644)
+	var cfg, err = readConfig(path)
+	if err != nil || cfg.Transport != "irc" || cfg.Channel != "#assassins" || cfg.IRC.Nick != "bot" || !cfg.Discord.IgnoreEdits || cfg.Lang != "en" {
+		t.Error("Unexpected config", cfg, err)
+	}
+	if cfg.Timing.Kind != "fixed" || cfg.Timing.Delay != "1m" {
+		t.Error("Unexpected timing", cfg.Timing)
+	}
+	var fixed = DefaultConfig.Timing
+	for _, c := range []Config{
+		{Lang: "xx", Timing: fixed, Words: path},
+		{Lang: "en", Timing: assassin.TimingConfig{Kind: "fixed", Delay: "soon"}, Words: path},
+		{Lang: "en", Timing: fixed},
+		{Lang: "en", Timing: assassin.TimingConfig{Kind: "sometimes"}, Words: path},
+		{Lang: "en", Timing: fixed, Intel: "often", Words: path},
+		{Lang: "en", Timing: fixed, Words: filepath.Join(t.TempDir(), "missing.txt")},
+	} {
+		if _, err := newBot(c); err == nil {
+			t.Error("Expected error", c)
+		}
+	}
+}
+
+func TestIRCConfig(t *testing.T) {
+	var b = &bot{cfg: DefaultConfig}
+	b.cfg.IRC.TLS = true
+	for addr, host := range map[string]string{"irc.example.org:6697": "irc.example.org", "[2001:db8::1]:6697": "2001:db8::1", "irc.example.org": ""} {
+		b.cfg.IRC.Addr = addr
+		var ic, err = b.ircConfig()
+		if host == "" && err == nil || host != "" && (err != nil || ic.TLS.ServerName != host) {
+			t.Error("Unexpected config for", addr, ic.TLS, err)
+		}
+	}
+}
+
+func TestAdmins(t *testing.T) {
+	var b = testBot(t, "apple pear")
+	b.cfg.Admins = []string{"U1"}
+	var cmd = b.commands(assassin.NewGameEngine(b.tpl, nil, b.timing, nil))
+	var mh = &messages{}
+	// Nb. the display name matches, but only the account counts
+	cmd.Handle(assassin.Talk{Channel: "c", Speaker: 2, Name: "U1", Account: "U2", Text: "!stop"}, mh)
+	cmd.Handle(assassin.Talk{Channel: "c", Speaker: 1, Name: "Ace", Account: "U1", Text: "!stop"}, mh)
+	if len(mh.a) != 2 || mh.a[0] != b.tpl.Fmt(b.tpl.CA, "stop") || mh.a[1] == mh.a[0] {
+		t.Error("Unexpected replies", mh.a)
+	}
+}
+
+// messages records announcements.
+type messages struct {
+	a []string
+}
+
+func (m *messages) Announce(s string)                  { m.a = append(m.a, s) }
+func (m *messages) Notify(p assassin.Player, s string) {}
+
+func TestParseLine(t *testing.T) {
+	if n, s, ok := parseLine("alice:  hello: there "); !ok || n != "alice" || s != "hello: there" {
+		t.Error("Unexpected parse", n, s, ok)
+	}
+	if _, _, ok := parseLine("hello"); ok {
+		t.Error("Unexpected parse")
+	}
+	if _, _, ok := parseLine(": hello"); ok {
+		t.Error("Unexpected parse")
+	}
+}
+
+func TestConsole(t *testing.T) {
+	t.Run("Players", func(t *testing.T) {
+		// with a single word, whoever says it is assassinated by their contract
+		var b = testBot(t, "apple", "alice", "bob")
+		var out bytes.Buffer
+		var e = assassin.NewGameEngine(b.tpl, nil, b.timing, nil)
+		var err = b.console(context.Background(), e, nil, strings.NewReader("carol: hi\nnonsense\nalice: an apple a day\n"), &out)
+		if err != nil {
+			t.Error(err)
+		}
+		for _, s := range []string{"The game has begun.", "(to alice) Your target is bob.", "carol is not playing.", "Say something as", "alice has been assassinated.", "bob wins."} {
+			if !strings.Contains(out.String(), s) {
+				t.Errorf("Missing %q in %q", s, out.String())
+			}
+		}
+	})
+	t.Run("Commands", func(t *testing.T) {
+		var b = testBot(t, "apple pear")
+		var out bytes.Buffer
+		var e = assassin.NewGameEngine(b.tpl, nil, b.timing, nil)
+		b.console(context.Background(), e, b.commands(e), strings.NewReader("alice: !join\nbob: !join\nalice: !list\n"), &out)
+		if !strings.Contains(out.String(), "Players: alice, bob.") {
+			t.Error("Unexpected output", out.String())
+		}
+	})
+}
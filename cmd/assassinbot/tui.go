@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/tui"
+)
+
+// stty runs the stty command on the terminal.
+func stty(args ...string) (string, error) {
+	var c = exec.Command("stty", args...)
+	c.Stdin = os.Stdin
+	var out, err = c.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// rawMode puts the terminal in raw mode, returning a function to restore it.
+func rawMode() (func(), error) {
+	var state, err = stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(state) }, nil
+}
+
+// hotseat plays a game between the configured players on a shared terminal, until it is quit.
+func (b *bot) hotseat(ctx context.Context) error {
+	if len(b.cfg.Players) < assassin.MinPlayers {
+		return errors.New(b.tpl.Fmt(b.tpl.CFP, assassin.MinPlayers))
+	}
+	var pl = make(map[assassin.ID]string, len(b.cfg.Players))
+	for i, n := range b.cfg.Players {
+		pl[assassin.ID(i+1)] = n
+	}
+	var ui = tui.New(pl, nil)
+	var e = assassin.NewGameEngine(b.tpl, ui, b.timing, nil)
+	ui.SetTalkHandler(func(t assassin.Talk) {
+		t.Game = 1
+		e.IncomingTalk(t)
+	})
+	var restore, err = rawMode()
+	if err != nil {
+		return fmt.Errorf("tui needs a terminal: %v", err)
+	}
+	defer restore()
+	var game, stop = context.WithCancel(ctx)
+	var done = make(chan struct{})
+	go func() {
+		defer close(done)
+		e.RunContext(game, b.newGame(pl))
+	}()
+	err = ui.Run(ctx, os.Stdin, os.Stdout)
+	stop()
+	<-done
+	fmt.Print("\r\n")
+	return err
+}
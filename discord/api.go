@@ -0,0 +1,87 @@
+package discord
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// HTTPError is returned when Discord rejects an API request.
+type HTTPError struct {
+	Status  int
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e HTTPError) Error() string {
+	return fmt.Sprintf("discord: %d %v (%d)", e.Status, e.Message, e.Code)
+}
+
+// httpAPI is an API sending requests to the Discord HTTP API.
+type httpAPI struct {
+	url, token string
+	client     *http.Client
+}
+
+// NewAPI returns an API sending requests to the Discord HTTP API at url, authorised by token.
+func NewAPI(url, token string, client *http.Client) API {
+	return &httpAPI{url, token, client}
+}
+
+// do sends a request to the API with a JSON body, decoding the JSON response into res.
+func (a *httpAPI) do(method, path string, body, res interface{}) error {
+	var b, err = json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, raw, err := chat.Retry(a.client, func() (*http.Request, error) {
+		var req, err = http.NewRequest(method, a.url+path, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bot "+a.token)
+		return req, nil
+	}, func(resp *http.Response, body []byte) time.Duration {
+		var rl struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		if json.Unmarshal(body, &rl) != nil {
+			rl.RetryAfter, _ = strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+		}
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e = &HTTPError{Status: resp.StatusCode}
+		json.Unmarshal(raw, e)
+		return e
+	}
+	if res != nil {
+		return json.Unmarshal(raw, res)
+	}
+	return nil
+}
+
+func (a *httpAPI) CreateMessage(channel, content string) error {
+	return a.do("POST", "channels/"+channel+"/messages", map[string]interface{}{
+		"content": content,
+		// game messages never ping anyone
+		"allowed_mentions": map[string][]string{"parse": {}},
+	}, nil)
+}
+
+func (a *httpAPI) CreateDM(user string) (string, error) {
+	var ch struct {
+		ID string `json:"id"`
+	}
+	var err = a.do("POST", "users/@me/channels", map[string]string{"recipient_id": user}, &ch)
+	return ch.ID, err
+}
@@ -0,0 +1,237 @@
+/*
+Package discord is a chat.Transport for a Discord guild channel.
+
+Messages are posted to the game channel, and private messages, such as each player's target and KillWord, are sent by direct message.
+When a message is edited, only the words added by the edit are passed on, unless Config.IgnoreEdits is set.
+
+The Bot talks to Discord through a Gateway, receiving events, and an API, sending messages.
+By default these connect to Discord itself, but either can be replaced, see package discordtest.
+
+Usage:
+
+	var b = discord.NewBot(discord.Config{Token: token, Channel: "123456789"})
+	var br = chat.NewBridge(b, engine.IncomingTalk)
+	go br.Run(ctx)
+*/
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+const (
+	// DefaultGatewayURL is the address of the Discord gateway.
+	DefaultGatewayURL = "wss://gateway.discord.gg/?v=10&encoding=json"
+	// DefaultAPIURL is the base URL of the Discord HTTP API.
+	DefaultAPIURL = "https://discord.com/api/v10/"
+	// Intents are the gateway intents needed to read guild messages: GUILD_MESSAGES and MESSAGE_CONTENT.
+	Intents = 1<<9 | 1<<15
+)
+
+// maxSeen is the number of recent messages remembered, to find the words added by edits.
+const maxSeen = 1000
+
+// Dispatch is an event dispatched by the gateway, such as MESSAGE_CREATE.
+type Dispatch struct {
+	Type string
+	Data json.RawMessage
+}
+
+// Gateway delivers Discord events.
+type Gateway interface {
+	// Run passes dispatched events to handle until ctx is done, reconnecting as needed.
+	Run(ctx context.Context, handle func(d Dispatch)) error
+}
+
+// API sends messages to Discord.
+type API interface {
+	// CreateMessage posts content to a channel.
+	CreateMessage(channel, content string) error
+	// CreateDM returns the ID of the direct message channel with user.
+	CreateDM(user string) (string, error)
+}
+
+// Config contains Discord settings. Zero values are replaced by defaults.
+type Config struct {
+	// Token is the bot's token.
+	Token string
+	// Channel is the ID of the game channel.
+	Channel string
+	// IgnoreEdits stops edited messages from being passed on as talk.
+	IgnoreEdits bool
+	// Gateway defaults to a websocket connection to GatewayURL, or DefaultGatewayURL.
+	Gateway    Gateway
+	GatewayURL string
+	// API defaults to HTTP requests to APIURL, or DefaultAPIURL, sent by Client, or http.DefaultClient.
+	API    API
+	APIURL string
+	Client *http.Client
+}
+
+// User is a Discord user.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// Message is a Discord message, as far as the Bot needs it.
+type Message struct {
+	ID              string     `json:"id"`
+	ChannelID       string     `json:"channel_id"`
+	Author          *User      `json:"author,omitempty"`
+	Content         string     `json:"content"`
+	Timestamp       time.Time  `json:"timestamp"`
+	EditedTimestamp *time.Time `json:"edited_timestamp,omitempty"`
+}
+
+// Bot is a Discord bot, a chat.Transport for a single channel.
+type Bot struct {
+	cfg   Config
+	mu    sync.Mutex
+	dms   map[string]string
+	seen  map[string]string
+	order []string
+}
+
+// NewBot creates a new Bot instance with settings cfg.
+func NewBot(cfg Config) *Bot {
+	if cfg.GatewayURL == "" {
+		cfg.GatewayURL = DefaultGatewayURL
+	}
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.Gateway == nil {
+		cfg.Gateway = NewGateway(cfg.GatewayURL, cfg.Token, Intents)
+	}
+	if cfg.API == nil {
+		cfg.API = NewAPI(cfg.APIURL, cfg.Token, cfg.Client)
+	}
+	return &Bot{
+		cfg:  cfg,
+		dms:  make(map[string]string),
+		seen: make(map[string]string),
+	}
+}
+
+// Run passes messages posted in the channel to recv, receiving events from the gateway until ctx is done.
+func (b *Bot) Run(ctx context.Context, recv func(m chat.Message)) error {
+	return b.cfg.Gateway.Run(ctx, func(d Dispatch) {
+		if m, ok := b.message(d); ok {
+			recv(m)
+		}
+	})
+}
+
+// Send text to the channel.
+func (b *Bot) Send(text string) error {
+	return b.cfg.API.CreateMessage(b.cfg.Channel, text)
+}
+
+// SendPrivate sends text to user by direct message.
+func (b *Bot) SendPrivate(user, text string) error {
+	b.mu.Lock()
+	var ch, open = b.dms[user]
+	b.mu.Unlock()
+	if !open {
+		var err error
+		if ch, err = b.cfg.API.CreateDM(user); err != nil {
+			return err
+		}
+		b.mu.Lock()
+		b.dms[user] = ch
+		b.mu.Unlock()
+	}
+	return b.cfg.API.CreateMessage(ch, text)
+}
+
+// remember the content of message id, returning its previous content if known.
+func (b *Bot) remember(id, content string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var old, ok = b.seen[id]
+	if !ok {
+		b.order = append(b.order, id)
+		if len(b.order) > maxSeen {
+			delete(b.seen, b.order[0])
+			b.order = b.order[1:]
+		}
+	}
+	b.seen[id] = content
+	return old, ok
+}
+
+// added returns the words of s that are not in old, in order.
+func added(old, s string) string {
+	var n = make(map[string]int)
+	for _, w := range strings.Fields(old) {
+		n[w]++
+	}
+	var ws = make([]string, 0)
+	for _, w := range strings.Fields(s) {
+		if n[w] > 0 {
+			n[w]--
+		} else {
+			ws = append(ws, w)
+		}
+	}
+	return strings.Join(ws, " ")
+}
+
+/*
+message returns the message in the channel dispatched by the gateway, if any.
+An edit is passed on as the words it added, and only if the original message was seen.
+*/
+func (b *Bot) message(d Dispatch) (chat.Message, bool) {
+	if d.Type != "MESSAGE_CREATE" && d.Type != "MESSAGE_UPDATE" {
+		return chat.Message{}, false
+	}
+	var m Message
+	if err := json.Unmarshal(d.Data, &m); err != nil {
+		return chat.Message{}, false
+	}
+	if m.ChannelID != b.cfg.Channel || m.Author == nil || m.Author.Bot {
+		return chat.Message{}, false
+	}
+	var cm = chat.Message{
+		Room:    m.ChannelID,
+		User:    m.Author.ID,
+		Name:    m.Author.GlobalName,
+		Account: m.Author.ID,
+		Time:    m.Timestamp,
+		Text:    m.Content,
+	}
+	if cm.Name == "" {
+		cm.Name = m.Author.Username
+	}
+	var old, ok = b.remember(m.ID, m.Content)
+	if d.Type == "MESSAGE_CREATE" && ok {
+		// replayed after a resume
+		return chat.Message{}, false
+	}
+	if d.Type == "MESSAGE_UPDATE" {
+		if b.cfg.IgnoreEdits || !ok {
+			return chat.Message{}, false
+		}
+		if cm.Text = added(old, m.Content); cm.Text == "" {
+			return chat.Message{}, false
+		}
+		cm.Time = time.Now()
+		if m.EditedTimestamp != nil {
+			cm.Time = *m.EditedTimestamp
+		}
+	}
+	return cm, true
+}
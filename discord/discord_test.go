@@ -0,0 +1,147 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/chat"
+	"github.com/joshringer/assassinbot/discord/discordtest"
+)
+
+func message(id, channel, user, content string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":         id,
+		"channel_id": channel,
+		"author":     map[string]interface{}{"id": user, "username": "u" + user, "global_name": "Ace", "bot": user == "bot"},
+		"content":    content,
+		"timestamp":  time.Now().Format(time.RFC3339Nano),
+	}
+}
+
+func TestBot(t *testing.T) {
+	var srv = discordtest.NewServer("token")
+	defer srv.Close()
+	srv.SetHeartbeat(100 * time.Millisecond)
+	var talk = make(chan assassin.Talk, 10)
+	var b = NewBot(Config{
+		Token:      "token",
+		Channel:    "C1",
+		GatewayURL: srv.GatewayURL(),
+		APIURL:     srv.APIURL(),
+	})
+	var br = chat.NewBridge(b, func(t assassin.Talk) { talk <- t })
+	var ctx, cancel = context.WithCancel(context.Background())
+	var res = make(chan error)
+	go func() { res <- br.Run(ctx) }()
+	var connected = func(t *testing.T, resumed bool) {
+		select {
+		case r := <-srv.Connected():
+			if r != resumed {
+				t.Error("Unexpected session, resumed:", r)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Bot not connected")
+		}
+	}
+	var dispatch = func(t *testing.T, typ string, d interface{}) {
+		if err := srv.Dispatch(typ, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var next = func(t *testing.T, text string) assassin.Talk {
+		select {
+		case tk := <-talk:
+			if tk.Text != text {
+				t.Error("Talk", tk.Text, "!=", text)
+			}
+			return tk
+		case <-time.After(time.Second):
+			t.Fatal("Talk", text, "not passed on")
+			return assassin.Talk{}
+		}
+	}
+	connected(t, false)
+	t.Run("talk", func(t *testing.T) {
+		dispatch(t, "MESSAGE_CREATE", message("m0", "C2", "U1", "elsewhere"))
+		dispatch(t, "MESSAGE_CREATE", message("m1", "C1", "bot", "from a bot"))
+		dispatch(t, "MESSAGE_CREATE", message("m2", "C1", "U1", "hello"))
+		if tk := next(t, "hello"); tk.Channel != "C1" || tk.Name != "Ace" || tk.Speaker != br.ID("U1") || tk.Time.IsZero() {
+			t.Error("Unexpected talk", tk)
+		}
+	})
+	t.Run("edits", func(t *testing.T) {
+		dispatch(t, "MESSAGE_UPDATE", message("m2", "C1", "U1", "hello kw"))
+		next(t, "kw")
+		dispatch(t, "MESSAGE_UPDATE", message("m2", "C1", "U1", "kw hello"))
+		dispatch(t, "MESSAGE_UPDATE", message("m9", "C1", "U1", "unseen kw"))
+		dispatch(t, "MESSAGE_CREATE", message("m3", "C1", "U1", "next"))
+		next(t, "next")
+	})
+	t.Run("resume", func(t *testing.T) {
+		srv.Disconnect()
+		connected(t, true)
+		dispatch(t, "MESSAGE_CREATE", message("m3", "C1", "U1", "next"))
+		dispatch(t, "MESSAGE_CREATE", message("m4", "C1", "U1", "resumed"))
+		next(t, "resumed")
+	})
+	t.Run("messages", func(t *testing.T) {
+		var p = assassin.Player{ID: br.ID("U1"), Name: "Ace"}
+		br.Announce(assassin.LangEn.GS)
+		br.Notify(p, assassin.LangEn.Fmt(assassin.LangEn.PT, "Bee", "kw"))
+		br.Notify(assassin.Player{ID: 99, Name: "Dee"}, "lost")
+		var ms = srv.Messages()
+		if len(ms) != 2 || ms[0] != (discordtest.Message{Channel: "C1", Content: assassin.LangEn.GS}) ||
+			ms[1] != (discordtest.Message{Channel: discordtest.DM("U1"), Content: "Your target is Bee. Your KillWord is kw."}) {
+			t.Error("Unexpected messages", ms)
+		}
+		if err := NewAPI(srv.APIURL(), "wrong", srv.Client()).CreateMessage("C1", "hi"); err == nil {
+			t.Error("Expected error with wrong token")
+		} else if e, ok := err.(*HTTPError); !ok || e.Status != 401 {
+			t.Error("Unexpected error", err)
+		}
+	})
+	cancel()
+	select {
+	case err := <-res:
+		if err != context.Canceled {
+			t.Error("Unexpected error", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Run not stopped")
+	}
+}
+
+// gateway dispatches a fixed list of events.
+type gateway []Dispatch
+
+func (g gateway) Run(ctx context.Context, handle func(d Dispatch)) error {
+	for _, d := range g {
+		handle(d)
+	}
+	return nil
+}
+
+func TestIgnoreEdits(t *testing.T) {
+	var g gateway
+	for _, d := range []struct {
+		typ string
+		m   map[string]interface{}
+	}{
+		{"MESSAGE_CREATE", message("m1", "C1", "U1", "hello")},
+		{"MESSAGE_UPDATE", message("m1", "C1", "U1", "hello kw")},
+	} {
+		var raw, _ = json.Marshal(d.m)
+		g = append(g, Dispatch{d.typ, raw})
+	}
+	var n int
+	NewBot(Config{Channel: "C1", IgnoreEdits: true, Gateway: g}).Run(context.Background(), func(m chat.Message) { n++ })
+	if n != 1 {
+		t.Error("Edit not ignored, talk count", n)
+	}
+	if a := added("a b a", "a kw b a a"); a != "kw a" {
+		t.Error("Unexpected added words", a)
+	}
+}
@@ -0,0 +1,205 @@
+/*
+Package discordtest provides an in-process stand-in for the Discord gateway and HTTP API, for testing bots offline.
+
+The Server accepts a single gateway connection at a time, identifying or resuming a session,
+and implements just enough of the HTTP API to post messages and open direct message channels,
+recording every message posted. Events are sent to the connected bot with Dispatch.
+*/
+package discordtest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/internal/websocket"
+)
+
+// Message is a message posted to the Server.
+type Message struct {
+	Channel, Content string
+}
+
+// Session is the ID of the gateway session created by the Server.
+const Session = "session"
+
+// ErrNotConnected is returned when dispatching an event with no bot connected.
+var ErrNotConnected = errors.New("discordtest: no gateway connection")
+
+// Server is a fake Discord gateway and HTTP API.
+type Server struct {
+	*httptest.Server
+	token     string
+	heartbeat time.Duration
+	mu        sync.Mutex
+	msgs      []Message
+	conn      *websocket.Conn
+	seq       int64
+	connected chan bool
+}
+
+// NewServer starts a new Server, accepting a bot with token. Close the Server when done.
+func NewServer(token string) *Server {
+	var s = &Server{token: token, heartbeat: 45 * time.Second, connected: make(chan bool, 10)}
+	var mux = http.NewServeMux()
+	mux.HandleFunc("/gateway", s.gateway)
+	mux.HandleFunc("/api/v10/", s.api)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// GatewayURL returns the address of the Server's gateway.
+func (s *Server) GatewayURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http") + "/gateway?v=10&encoding=json"
+}
+
+// APIURL returns the base URL of the Server's HTTP API.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/v10/"
+}
+
+// SetHeartbeat sets the heartbeat interval asked of bots connecting after the call.
+func (s *Server) SetHeartbeat(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.heartbeat = d
+}
+
+/*
+Connected returns a channel receiving a value each time a bot starts a gateway session:
+false when identifying for a new session, true when resuming.
+*/
+func (s *Server) Connected() <-chan bool {
+	return s.connected
+}
+
+// Messages returns the messages posted so far, in order.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+// DM returns the ID of the direct message channel with user.
+func DM(user string) string {
+	return "dm-" + user
+}
+
+// Dispatch sends an event of type t with data d to the connected bot.
+func (s *Server) Dispatch(t string, d interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+	s.seq++
+	return s.conn.WriteJSON(map[string]interface{}{"op": 0, "s": s.seq, "t": t, "d": d})
+}
+
+// Disconnect drops the gateway connection, as Discord does from time to time. The bot may resume the session.
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close(4000)
+		s.conn = nil
+	}
+}
+
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+}
+
+func (s *Server) gateway(w http.ResponseWriter, r *http.Request) {
+	var c, err = websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	var hb = s.heartbeat
+	s.mu.Unlock()
+	c.WriteJSON(map[string]interface{}{"op": 10, "d": map[string]int64{"heartbeat_interval": hb.Milliseconds()}})
+	var p payload
+	if err := c.ReadJSON(&p); err != nil {
+		return
+	}
+	var auth struct {
+		Token     string `json:"token"`
+		SessionID string `json:"session_id"`
+	}
+	json.Unmarshal(p.D, &auth)
+	if auth.Token != s.token || p.Op != 2 && (p.Op != 6 || auth.SessionID != Session) {
+		c.Close(4004)
+		return
+	}
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close(4000)
+	}
+	s.conn = c
+	s.mu.Unlock()
+	if p.Op == 2 {
+		s.Dispatch("READY", map[string]interface{}{
+			"session_id":         Session,
+			"resume_gateway_url": "ws" + strings.TrimPrefix(s.URL, "http") + "/gateway",
+			"user":               map[string]interface{}{"id": "bot", "username": "assassinbot", "bot": true},
+		})
+	} else {
+		s.Dispatch("RESUMED", nil)
+	}
+	s.connected <- p.Op == 6
+	for {
+		if err := c.ReadJSON(&p); err != nil {
+			break
+		}
+		if p.Op == 1 {
+			c.WriteJSON(map[string]interface{}{"op": 11})
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == c {
+		s.conn = nil
+	}
+}
+
+func reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) api(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bot "+s.token {
+		reply(w, http.StatusUnauthorized, map[string]interface{}{"message": "401: Unauthorized", "code": 0})
+		return
+	}
+	var path = strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v10/"), "/")
+	switch {
+	case r.Method == "POST" && len(path) == 3 && path[0] == "channels" && path[2] == "messages":
+		var m struct {
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.Content == "" {
+			reply(w, http.StatusBadRequest, map[string]interface{}{"message": "Cannot send an empty message", "code": 50006})
+			return
+		}
+		s.mu.Lock()
+		s.msgs = append(s.msgs, Message{path[1], m.Content})
+		s.mu.Unlock()
+		reply(w, http.StatusOK, map[string]interface{}{"channel_id": path[1], "content": m.Content})
+	case r.Method == "POST" && strings.Join(path, "/") == "users/@me/channels":
+		var dm struct {
+			RecipientID string `json:"recipient_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&dm)
+		reply(w, http.StatusOK, map[string]interface{}{"id": DM(dm.RecipientID), "type": 1})
+	default:
+		reply(w, http.StatusNotFound, map[string]interface{}{"message": "404: Not Found", "code": 0})
+	}
+}
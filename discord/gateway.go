@@ -0,0 +1,219 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/internal/websocket"
+)
+
+// Gateway opcodes.
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// payload is a gateway message.
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d,omitempty"`
+	S  int64           `json:"s,omitempty"`
+	T  string          `json:"t,omitempty"`
+}
+
+// send is a gateway message being sent.
+type send struct {
+	Op int         `json:"op"`
+	D  interface{} `json:"d"`
+}
+
+// ErrGatewayClosed is returned by the gateway when Discord refuses to let the bot reconnect, eg. for a bad token.
+var ErrGatewayClosed = errors.New("discord: gateway closed")
+
+// errReconnect ends a session that should be reconnected.
+var errReconnect = errors.New("discord: reconnect")
+
+// wsGateway is a Gateway connecting to Discord over a websocket.
+type wsGateway struct {
+	url, token string
+	intents    int
+	backoff    time.Duration
+	// session state, kept to resume after a reconnect
+	session, resume string
+	seq             int64
+}
+
+/*
+NewGateway returns a Gateway connecting to the Discord gateway at url with token, requesting intents.
+Lost connections are resumed where possible, so that no events are missed.
+*/
+func NewGateway(url, token string, intents int) Gateway {
+	return &wsGateway{url: url, token: token, intents: intents, backoff: time.Second}
+}
+
+func (g *wsGateway) Run(ctx context.Context, handle func(d Dispatch)) error {
+	var wait time.Duration
+	for {
+		var ok, err = g.connect(ctx, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if ce, ok := err.(*websocket.CloseError); ok {
+			switch {
+			case ce.Code == 4004 || ce.Code >= 4010 && ce.Code <= 4014:
+				// authentication failed, or the bot asked for something it cannot have
+				return ErrGatewayClosed
+			case ce.Code == 4007 || ce.Code == 4009:
+				// the session cannot be resumed
+				g.session, g.resume, g.seq = "", "", 0
+			}
+		}
+		if ok {
+			// reconnect straight away after a working session, and back off if that fails
+			wait = 0
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if wait *= 2; wait == 0 {
+			wait = g.backoff
+		} else if wait > time.Minute {
+			wait = time.Minute
+		}
+	}
+}
+
+/*
+connect runs a single gateway session, returning once the connection is lost.
+Reports whether the gateway said hello, so that the session got started.
+*/
+func (g *wsGateway) connect(ctx context.Context, handle func(d Dispatch)) (bool, error) {
+	var url = g.url
+	if g.session != "" && g.resume != "" {
+		// the resume URL comes without the version and encoding query
+		url = g.resume
+		if i := strings.IndexByte(g.url, '?'); i >= 0 && !strings.Contains(url, "?") {
+			url += g.url[i:]
+		}
+	}
+	var c, err = websocket.Dial(ctx, url, nil)
+	if err != nil {
+		return false, err
+	}
+	var done = make(chan struct{})
+	defer close(done)
+	defer c.Close(websocket.CloseNormal)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close(websocket.CloseNormal)
+		case <-done:
+		}
+	}()
+	var p payload
+	if err := c.ReadJSON(&p); err != nil {
+		return false, err
+	}
+	var hello struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+	if p.Op != opHello || json.Unmarshal(p.D, &hello) != nil || hello.HeartbeatInterval <= 0 {
+		return false, errors.New("discord: expected hello")
+	}
+	if g.session != "" {
+		err = c.WriteJSON(send{opResume, map[string]interface{}{"token": g.token, "session_id": g.session, "seq": g.seq}})
+	} else {
+		err = c.WriteJSON(send{opIdentify, map[string]interface{}{
+			"token":      g.token,
+			"intents":    g.intents,
+			"properties": map[string]string{"os": "linux", "browser": "assassinbot", "device": "assassinbot"},
+		}})
+	}
+	if err != nil {
+		return true, err
+	}
+	var (
+		mu    sync.Mutex
+		acked = true
+		seq   = g.seq
+		beat  = func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			acked = false
+			var s interface{}
+			if seq > 0 {
+				s = seq
+			}
+			return c.WriteJSON(send{opHeartbeat, s})
+		}
+	)
+	go func() {
+		var t = time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				mu.Lock()
+				var zombie = !acked
+				mu.Unlock()
+				if zombie {
+					// no reply to the last heartbeat, so the connection is dead
+					c.Close(4000)
+					return
+				}
+				beat()
+			case <-done:
+				return
+			}
+		}
+	}()
+	for {
+		var p payload
+		if err := c.ReadJSON(&p); err != nil {
+			return true, err
+		}
+		switch p.Op {
+		case opDispatch:
+			mu.Lock()
+			seq = p.S
+			mu.Unlock()
+			g.seq = p.S
+			if p.T == "READY" {
+				var ready struct {
+					SessionID        string `json:"session_id"`
+					ResumeGatewayURL string `json:"resume_gateway_url"`
+				}
+				json.Unmarshal(p.D, &ready)
+				g.session, g.resume = ready.SessionID, ready.ResumeGatewayURL
+			}
+			handle(Dispatch{p.T, p.D})
+		case opHeartbeat:
+			beat()
+		case opHeartbeatACK:
+			mu.Lock()
+			acked = true
+			mu.Unlock()
+		case opReconnect:
+			return true, errReconnect
+		case opInvalidSession:
+			var resumable bool
+			json.Unmarshal(p.D, &resumable)
+			if !resumable {
+				g.session, g.resume, g.seq = "", "", 0
+			}
+			return true, errReconnect
+		}
+	}
+}
@@ -0,0 +1,364 @@
+/*
+Package websocket is a minimal RFC 6455 WebSocket implementation, covering what the bot frontends need:
+dialing and accepting connections, and exchanging whole text or binary messages.
+Extensions and subprotocols are not supported.
+*/
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message types, as frame opcodes.
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// Close codes.
+const (
+	CloseNormal    = 1000
+	CloseGoingAway = 1001
+	CloseNoStatus  = 1005
+	CloseTooBig    = 1009
+)
+
+// MaxMessageSize is the largest message accepted by ReadMessage.
+const MaxMessageSize = 16 << 20
+
+// magic is appended to the handshake key, see RFC 6455 section 1.3.
+const magic = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake is returned when the opening handshake fails.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// CloseError is returned by ReadMessage once the peer has closed the connection.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed %d %v", e.Code, e.Text)
+}
+
+// Conn is a WebSocket connection. Messages may be written concurrently, but only read by one goroutine at a time.
+type Conn struct {
+	c      net.Conn
+	r      *bufio.Reader
+	client bool
+	wmu    sync.Mutex
+	closed bool
+}
+
+func accept(key string) string {
+	var h = sha1.Sum([]byte(key + magic))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// Dial opens a WebSocket connection to a ws:// or wss:// URL, sending extra request headers h.
+func Dial(ctx context.Context, rawurl string, h http.Header) (*Conn, error) {
+	var u, err = url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	var host = u.Host
+	var secure bool
+	switch u.Scheme {
+	case "ws", "http":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss", "https":
+		secure = true
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %v", u.Scheme)
+	}
+	var c net.Conn
+	if secure {
+		var d = &tls.Dialer{Config: &tls.Config{ServerName: u.Hostname()}}
+		c, err = d.DialContext(ctx, "tcp", host)
+	} else {
+		var d net.Dialer
+		c, err = d.DialContext(ctx, "tcp", host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var k = make([]byte, 16)
+	rand.Read(k)
+	var key = base64.StdEncoding.EncodeToString(k)
+	var req = &http.Request{
+		Method:     "GET",
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, v := range h {
+		req.Header[k] = v
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if dl, ok := ctx.Deadline(); ok {
+		c.SetDeadline(dl)
+	}
+	if err := req.Write(c); err != nil {
+		c.Close()
+		return nil, err
+	}
+	var r = bufio.NewReader(c)
+	resp, err := http.ReadResponse(r, req)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != accept(key) {
+		c.Close()
+		return nil, ErrBadHandshake
+	}
+	c.SetDeadline(time.Time{})
+	return &Conn{c: c, r: r, client: true}, nil
+}
+
+// hasToken reports whether comma separated header h contains token, ignoring case.
+func hasToken(h, token string) bool {
+	for _, t := range strings.Split(h, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Upgrade the HTTP server connection of request r to a WebSocket connection.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	var key = r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != "GET" || !hasToken(r.Header.Get("Connection"), "upgrade") ||
+		!hasToken(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, ErrBadHandshake.Error(), http.StatusBadRequest)
+		return nil, ErrBadHandshake
+	}
+	var hj, ok = w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket: cannot hijack connection", http.StatusInternalServerError)
+		return nil, ErrBadHandshake
+	}
+	c, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + accept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return &Conn{c: c, r: rw.Reader}, nil
+}
+
+// writeFrame writes a single, final frame. Client frames are masked.
+func (c *Conn) writeFrame(op int, p []byte) error {
+	var h = make([]byte, 2, 14)
+	h[0] = 0x80 | byte(op)
+	switch {
+	case len(p) < 126:
+		h[1] = byte(len(p))
+	case len(p) <= 0xffff:
+		h[1] = 126
+		h = binary.BigEndian.AppendUint16(h, uint16(len(p)))
+	default:
+		h[1] = 127
+		h = binary.BigEndian.AppendUint64(h, uint64(len(p)))
+	}
+	if c.client {
+		h[1] |= 0x80
+		var m = make([]byte, 4)
+		rand.Read(m)
+		h = append(h, m...)
+		var q = make([]byte, len(p))
+		for i := range p {
+			q[i] = p[i] ^ m[i%4]
+		}
+		p = q
+	}
+	c.c.SetWriteDeadline(time.Now().Add(time.Minute))
+	if _, err := c.c.Write(append(h, p...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// WriteMessage sends a whole message of type op.
+func (c *Conn) WriteMessage(op int, p []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	return c.writeFrame(op, p)
+}
+
+// WriteJSON sends v encoded as a JSON text message.
+func (c *Conn) WriteJSON(v interface{}) error {
+	var p, err = json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteMessage(TextMessage, p)
+}
+
+// readFrame reads a single frame, unmasking its payload.
+func (c *Conn) readFrame() (fin bool, op int, p []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.r, h[:]); err != nil {
+		return
+	}
+	fin, op = h[0]&0x80 != 0, int(h[0]&0x0f)
+	var n = uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.r, b[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.r, b[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if n > MaxMessageSize {
+		err = &CloseError{CloseTooBig, "message too big"}
+		return
+	}
+	var m [4]byte
+	var masked = h[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(c.r, m[:]); err != nil {
+			return
+		}
+	}
+	p = make([]byte, n)
+	if _, err = io.ReadFull(c.r, p); err != nil {
+		return
+	}
+	if masked {
+		for i := range p {
+			p[i] ^= m[i%4]
+		}
+	}
+	return
+}
+
+/*
+ReadMessage reads the next whole text or binary message.
+Pings are answered while waiting. Once the peer closes the connection, a *CloseError is returned.
+*/
+func (c *Conn) ReadMessage() (op int, p []byte, err error) {
+	for {
+		var fin, fop, fp, err = c.readFrame()
+		if err != nil {
+			if ce, ok := err.(*CloseError); ok {
+				c.close(ce.Code, ce.Text)
+			}
+			return 0, nil, err
+		}
+		switch fop {
+		case PingMessage:
+			c.WriteMessage(PongMessage, fp)
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			var ce = &CloseError{Code: CloseNoStatus}
+			if len(fp) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(fp))
+				ce.Text = string(fp[2:])
+			}
+			c.close(ce.Code, "")
+			return 0, nil, ce
+		case 0:
+			if op == 0 {
+				return 0, nil, errors.New("websocket: unexpected continuation frame")
+			}
+		default:
+			op = fop
+			p = p[:0]
+		}
+		if len(p)+len(fp) > MaxMessageSize {
+			c.close(CloseTooBig, "message too big")
+			return 0, nil, &CloseError{CloseTooBig, "message too big"}
+		}
+		p = append(p, fp...)
+		if fin {
+			return op, p, nil
+		}
+	}
+}
+
+// ReadJSON reads the next message, decoding it from JSON into v.
+func (c *Conn) ReadJSON(v interface{}) error {
+	var _, p, err = c.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(p, v)
+}
+
+// SetReadDeadline sets the deadline for reading the next message.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.c.SetReadDeadline(t)
+}
+
+/*
+close sends a close frame with code and text, unless already sent, then closes the connection.
+CloseNoStatus must not be sent, see RFC 6455 section 7.4.1, so the close frame is left empty instead.
+*/
+func (c *Conn) close(code int, text string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	var p []byte
+	if code != CloseNoStatus {
+		p = append(binary.BigEndian.AppendUint16(nil, uint16(code)), text...)
+	}
+	c.writeFrame(CloseMessage, p)
+	return c.c.Close()
+}
+
+// Close the connection with code, after telling the peer.
+func (c *Conn) Close(code int) error {
+	return c.close(code, "")
+}
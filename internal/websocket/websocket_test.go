@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	// echo server, closing after a "bye" message
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var c, err = Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		for {
+			var op, p, err = c.ReadMessage()
+			if err != nil {
+				return
+			}
+			if string(p) == "bye" {
+				c.Close(CloseGoingAway)
+				return
+			}
+			c.WriteMessage(PingMessage, []byte("ping"))
+			c.WriteMessage(op, p)
+		}
+	}))
+	defer srv.Close()
+	var c, err = Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range [][]byte{[]byte("hello"), bytes.Repeat([]byte("x"), 200), bytes.Repeat([]byte("y"), 70000)} {
+		if err := c.WriteMessage(BinaryMessage, p); err != nil {
+			t.Fatal(err)
+		}
+		if op, e, err := c.ReadMessage(); err != nil || op != BinaryMessage || !bytes.Equal(p, e) {
+			t.Error("Unexpected echo", op, len(e), err)
+		}
+	}
+	t.Run("JSON", func(t *testing.T) {
+		var v struct{ A int }
+		c.WriteJSON(map[string]int{"A": 1})
+		if err := c.ReadJSON(&v); err != nil || v.A != 1 {
+			t.Error("Unexpected echo", v, err)
+		}
+	})
+	t.Run("Close", func(t *testing.T) {
+		c.WriteMessage(TextMessage, []byte("bye"))
+		var _, _, err = c.ReadMessage()
+		if ce, ok := err.(*CloseError); !ok || ce.Code != CloseGoingAway {
+			t.Error("Unexpected error", err)
+		}
+		if err := c.WriteMessage(TextMessage, []byte("gone")); err == nil {
+			t.Error("Expected error writing to closed connection")
+		}
+	})
+	t.Run("CloseNoStatus", func(t *testing.T) {
+		var c, err = Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/echo", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a close frame without a status is answered in kind, not with 1005
+		c.writeFrame(CloseMessage, nil)
+		if _, op, p, err := c.readFrame(); err != nil || op != CloseMessage || len(p) != 0 {
+			t.Error("Unexpected reply", op, p, err)
+		}
+	})
+	t.Run("BadHandshake", func(t *testing.T) {
+		var resp, err = http.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Error("Unexpected status", resp.Status)
+		}
+	})
+}
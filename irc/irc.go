@@ -0,0 +1,437 @@
+/*
+Package irc is a chat.Transport for an IRC channel.
+
+Messages are sent to the game channel, and private messages to the player's nick.
+Users are identified by the nick they were first seen with, and keep it across nick changes.
+Nicks are not authenticated, so where the server supports the account-tag capability,
+each message's Account is the services account its sender is logged in to.
+Outgoing messages are rate limited to avoid being kicked for flooding,
+and the Client reconnects whenever the connection to the server is lost.
+
+Usage:
+
+	var c = irc.NewClient(irc.Config{Addr: "irc.example.net:6667", Nick: "assassinbot", Channel: "#assassin"})
+	var b = chat.NewBridge(c, engine.IncomingTalk)
+	go b.Run(ctx)
+*/
+package irc
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// maxText is the longest message text sent in a single line, leaving room for the command and server prefix.
+const maxText = 400
+
+// Config contains IRC connection settings. Zero values are replaced by defaults.
+type Config struct {
+	// Addr is the server's host:port.
+	Addr string
+	// TLS, if not nil, is used to connect over TLS.
+	TLS      *tls.Config
+	Password string
+	Nick     string
+	// User and Name default to Nick.
+	User, Name string
+	Channel    string
+	// At most Burst messages are sent at once, after which one message is sent every Rate.
+	Rate  time.Duration
+	Burst int
+	// Reconnects are delayed by Backoff, doubling after each failed attempt up to MaxBackoff.
+	Backoff, MaxBackoff time.Duration
+}
+
+// DefaultConfig contains the default settings for Config.
+var DefaultConfig = Config{
+	Nick:       "assassinbot",
+	Rate:       2 * time.Second,
+	Burst:      5,
+	Backoff:    time.Second,
+	MaxBackoff: 5 * time.Minute,
+}
+
+// Client is an IRC client, a chat.Transport for a single channel.
+type Client struct {
+	cfg   Config
+	out   chan string
+	mu    sync.Mutex
+	recv  func(m chat.Message)
+	nick  string
+	users map[string]string
+	nicks map[string]string
+}
+
+// NewClient creates a new Client instance with settings cfg.
+func NewClient(cfg Config) *Client {
+	var d = DefaultConfig
+	if cfg.Nick == "" {
+		cfg.Nick = d.Nick
+	}
+	if cfg.User == "" {
+		cfg.User = cfg.Nick
+	}
+	if cfg.Name == "" {
+		cfg.Name = cfg.Nick
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = d.Rate
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = d.Burst
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = d.Backoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = d.MaxBackoff
+	}
+	return &Client{
+		cfg:   cfg,
+		out:   make(chan string, 256),
+		nick:  cfg.Nick,
+		users: make(map[string]string),
+		nicks: make(map[string]string),
+	}
+}
+
+// fold returns nick in lower case, using the RFC 1459 case mapping.
+func fold(nick string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '[':
+			return '{'
+		case ']':
+			return '}'
+		case '\\':
+			return '|'
+		case '~':
+			return '^'
+		}
+		return r
+	}, strings.ToLower(nick))
+}
+
+// user returns the user with nick, the folded nick they were first seen with.
+func (c *Client) user(nick string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if u, ok := c.users[fold(nick)]; ok {
+		return u
+	}
+	var u = fold(nick)
+	c.users[u] = u
+	c.nicks[u] = nick
+	return u
+}
+
+// Nick returns the current nick of user.
+func (c *Client) Nick(user string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var n, ok = c.nicks[user]
+	return n, ok
+}
+
+// rename follows a nick change, so the user stays the same.
+func (c *Client) rename(old, nick string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fold(old) == fold(c.nick) {
+		c.nick = nick
+		return
+	}
+	if u, ok := c.users[fold(old)]; ok {
+		delete(c.users, fold(old))
+		c.users[fold(nick)] = u
+		c.nicks[u] = nick
+	}
+}
+
+// split s into lines short enough to send.
+func split(s string) []string {
+	var ls = make([]string, 0, 1)
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimRight(l, "\r")
+		for len(l) > maxText {
+			var i = maxText
+			for i > 0 && !utf8.RuneStart(l[i]) {
+				i--
+			}
+			ls = append(ls, l[:i])
+			l = l[i:]
+		}
+		if l != "" {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+func (c *Client) privmsg(to, s string) {
+	for _, l := range split(s) {
+		c.out <- "PRIVMSG " + to + " :" + l
+	}
+}
+
+// Send text to the channel.
+func (c *Client) Send(text string) error {
+	c.privmsg(c.cfg.Channel, text)
+	return nil
+}
+
+// SendPrivate sends text privately to user, by their current nick.
+func (c *Client) SendPrivate(user, text string) error {
+	var n, ok = c.Nick(user)
+	if !ok {
+		n = user
+	}
+	c.privmsg(n, text)
+	return nil
+}
+
+/*
+Run connects to the server and joins the channel, passing messages said there to recv, until ctx is done.
+Whenever the connection is lost, Run reconnects after a backoff delay.
+Messages sent while disconnected are queued, and delivered after reconnecting.
+*/
+func (c *Client) Run(ctx context.Context, recv func(m chat.Message)) error {
+	c.mu.Lock()
+	c.recv = recv
+	c.mu.Unlock()
+	var wait = c.cfg.Backoff
+	for {
+		// Nb. connection errors are only retried, never returned.
+		if ok, _ := c.session(ctx); ok {
+			wait = c.cfg.Backoff
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if wait *= 2; wait > c.cfg.MaxBackoff {
+			wait = c.cfg.MaxBackoff
+		}
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	if c.cfg.TLS != nil {
+		var d = &tls.Dialer{Config: c.cfg.TLS}
+		return d.DialContext(ctx, "tcp", c.cfg.Addr)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, "tcp", c.cfg.Addr)
+}
+
+// writer writes lines to a connection, shared by the session and its queue.
+type writer struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *writer) line(s string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(time.Minute))
+	var _, err = io.WriteString(w.conn, s+"\r\n")
+	return err
+}
+
+/*
+session runs a single connection to the server, returning once it is closed.
+Reports whether the client registered with the server.
+*/
+func (c *Client) session(ctx context.Context) (bool, error) {
+	var conn, err = c.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	var done = make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	var (
+		w          = &writer{conn: conn}
+		nick       = c.cfg.Nick
+		registered bool
+	)
+	w.line("CAP REQ :account-tag")
+	if c.cfg.Password != "" {
+		w.line("PASS " + c.cfg.Password)
+	}
+	w.line("NICK " + nick)
+	w.line("USER " + c.cfg.User + " 0 * :" + c.cfg.Name)
+	var s = bufio.NewScanner(conn)
+	for s.Scan() {
+		var m = parse(s.Text())
+		switch m.command {
+		case "PING":
+			w.line("PONG :" + m.param(0))
+		case "CAP":
+			if sub := m.param(1); sub == "ACK" || sub == "NAK" {
+				w.line("CAP END")
+			}
+		case "001":
+			registered = true
+			c.mu.Lock()
+			c.nick = m.param(0)
+			c.mu.Unlock()
+			w.line("JOIN " + c.cfg.Channel)
+			go c.flush(w, done)
+		case "433":
+			// nick in use
+			if !registered {
+				nick += "_"
+				w.line("NICK " + nick)
+			}
+		case "NICK":
+			c.rename(m.nick(), m.param(0))
+		case "PRIVMSG":
+			c.heard(m)
+		}
+	}
+	return registered, s.Err()
+}
+
+/*
+flush sends queued lines until done, at most Burst at once and then one every Rate.
+A line being held back when the session ends is dropped.
+*/
+func (c *Client) flush(w *writer, done <-chan struct{}) {
+	var t time.Time
+	for {
+		select {
+		case s := <-c.out:
+			var now = time.Now()
+			if t.Before(now) {
+				t = now
+			}
+			if d := t.Sub(now) - time.Duration(c.cfg.Burst-1)*c.cfg.Rate; d > 0 {
+				select {
+				case <-time.After(d):
+				case <-done:
+					return
+				}
+			}
+			t = t.Add(c.cfg.Rate)
+			if err := w.line(s); err != nil {
+				w.conn.Close()
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// heard a PRIVMSG, passing it on if it was said in the channel.
+func (c *Client) heard(m message) {
+	if fold(m.param(0)) != fold(c.cfg.Channel) {
+		return
+	}
+	var text = m.param(1)
+	if strings.HasPrefix(text, "\x01") {
+		// CTCP: only actions are talk
+		if !strings.HasPrefix(text, "\x01ACTION ") {
+			return
+		}
+		text = strings.TrimSuffix(strings.TrimPrefix(text, "\x01ACTION "), "\x01")
+	}
+	var nick = m.nick()
+	c.mu.Lock()
+	var recv = c.recv
+	c.mu.Unlock()
+	recv(chat.Message{Room: c.cfg.Channel, User: c.user(nick), Name: nick, Account: m.tags["account"], Time: time.Now(), Text: text})
+}
+
+// message is a parsed IRC protocol message.
+type message struct {
+	tags            map[string]string
+	prefix, command string
+	params          []string
+}
+
+// unescapeTag converts an escaped message tag value to plain text.
+var unescapeTag = strings.NewReplacer(`\:`, ";", `\s`, " ", `\\`, `\`, `\r`, "\r", `\n`, "\n").Replace
+
+// parse a line received from the server.
+func parse(line string) message {
+	var m message
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "@") {
+		var i = strings.IndexByte(line, ' ')
+		if i < 0 {
+			return m
+		}
+		m.tags = make(map[string]string)
+		for _, t := range strings.Split(line[1:i], ";") {
+			var k, v, _ = strings.Cut(t, "=")
+			m.tags[k] = unescapeTag(v)
+		}
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+	if strings.HasPrefix(line, ":") {
+		var i = strings.IndexByte(line, ' ')
+		if i < 0 {
+			m.prefix = line[1:]
+			return m
+		}
+		m.prefix = line[1:i]
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+	var ps = make([]string, 0, 4)
+	for line != "" {
+		if line[0] == ':' && len(ps) > 0 {
+			ps = append(ps, line[1:])
+			break
+		}
+		var i = strings.IndexByte(line, ' ')
+		if i < 0 {
+			ps = append(ps, line)
+			break
+		}
+		ps = append(ps, line[:i])
+		line = strings.TrimLeft(line[i+1:], " ")
+	}
+	if len(ps) > 0 {
+		m.command = strings.ToUpper(ps[0])
+		m.params = ps[1:]
+	}
+	return m
+}
+
+// param returns the ith parameter of m, or "" if missing.
+func (m message) param(i int) string {
+	if i < len(m.params) {
+		return m.params[i]
+	}
+	return ""
+}
+
+// nick returns the nick of the message's sender.
+func (m message) nick() string {
+	if i := strings.IndexByte(m.prefix, '!'); i >= 0 {
+		return m.prefix[:i]
+	}
+	return m.prefix
+}
@@ -0,0 +1,175 @@
+package irc
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// fakeServer accepts client connections on a local port.
+type fakeServer struct {
+	t     *testing.T
+	l     net.Listener
+	conns chan net.Conn
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	var l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s = &fakeServer{t, l, make(chan net.Conn, 1)}
+	go func() {
+		for {
+			var c, err = l.Accept()
+			if err != nil {
+				return
+			}
+			s.conns <- c
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) accept() *fakeConn {
+	select {
+	case c := <-s.conns:
+		return &fakeConn{s.t, c, bufio.NewReader(c)}
+	case <-time.After(time.Second):
+		s.t.Fatal("No connection within 1s")
+		return nil
+	}
+}
+
+type fakeConn struct {
+	t *testing.T
+	c net.Conn
+	r *bufio.Reader
+}
+
+// expect the next line from the client to be want.
+func (f *fakeConn) expect(want string) {
+	f.c.SetReadDeadline(time.Now().Add(time.Second))
+	var l, err = f.r.ReadString('\n')
+	if err != nil {
+		f.t.Fatal("Expected", want, "got error", err)
+	}
+	if l = strings.TrimRight(l, "\r\n"); l != want {
+		f.t.Error("Received", l, "!=", want)
+	}
+}
+
+func (f *fakeConn) send(l string) {
+	f.c.Write([]byte(l + "\r\n"))
+}
+
+func TestClient(t *testing.T) {
+	var s = newFakeServer(t)
+	defer s.l.Close()
+	var talk = make(chan assassin.Talk, 1)
+	var c = NewClient(Config{
+		Addr:    s.l.Addr().String(),
+		Nick:    "bot",
+		Channel: "#game",
+		Rate:    20 * time.Millisecond,
+		Burst:   2,
+		Backoff: time.Millisecond,
+	})
+	var b = chat.NewBridge(c, func(t assassin.Talk) { talk <- t })
+	var ctx, cancel = context.WithCancel(context.Background())
+	var res = make(chan error)
+	go func() { res <- b.Run(ctx) }()
+	var f = s.accept()
+	t.Run("register", func(t *testing.T) {
+		f.t = t
+		f.expect("CAP REQ :account-tag")
+		f.expect("NICK bot")
+		f.expect("USER bot 0 * :bot")
+		f.send(":srv CAP * ACK :account-tag")
+		f.expect("CAP END")
+		f.send(":srv 433 * bot :Nickname is already in use")
+		f.expect("NICK bot_")
+		f.send(":srv 001 bot_ :Welcome")
+		f.expect("JOIN #game")
+		f.send("PING :srv")
+		f.expect("PONG :srv")
+	})
+	t.Run("talk", func(t *testing.T) {
+		f.t = t
+		f.send("@account=ace :Ace!a@host PRIVMSG #game :hello there")
+		f.send(":Ace!a@host PRIVMSG bot_ :private")
+		f.send(":Ace!a@host NICK :Ace[m]")
+		f.send(":Ace[M]!a@host PRIVMSG #GAME :\x01ACTION waves\x01")
+		for _, want := range []struct{ text, account string }{{"hello there", "ace"}, {"waves", ""}} {
+			select {
+			case tk := <-talk:
+				if tk.Text != want.text || tk.Account != want.account || tk.Channel != "#game" || tk.Speaker != b.ID("ace") || tk.Name == "" {
+					t.Error("Unexpected talk", tk)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Talk", want.text, "not passed on")
+			}
+		}
+		if n, _ := c.Nick("ace"); n != "Ace[m]" {
+			t.Error("Nick change not followed", n)
+		}
+	})
+	t.Run("messages", func(t *testing.T) {
+		f.t = t
+		var start = time.Now()
+		b.Announce("one\ntwo")
+		b.Notify(assassin.Player{ID: b.ID("ace")}, "secret")
+		b.Notify(assassin.Player{ID: 99, Name: "Dee"}, "lost")
+		c.SendPrivate("Dee", "hi")
+		f.expect("PRIVMSG #game :one")
+		f.expect("PRIVMSG #game :two")
+		f.expect("PRIVMSG Ace[m] :secret")
+		f.expect("PRIVMSG Dee :hi")
+		if d := time.Since(start); d < 40*time.Millisecond {
+			t.Error("Messages not rate limited, sent within", d)
+		}
+	})
+	t.Run("reconnect", func(t *testing.T) {
+		f.c.Close()
+		f = s.accept()
+		f.t = t
+		f.expect("CAP REQ :account-tag")
+		f.expect("NICK bot")
+		f.expect("USER bot 0 * :bot")
+		b.Announce("back")
+		f.send(":srv 001 bot :Welcome")
+		f.expect("JOIN #game")
+		f.expect("PRIVMSG #game :back")
+	})
+	cancel()
+	select {
+	case err := <-res:
+		if err != context.Canceled {
+			t.Error("Unexpected error", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Run not stopped")
+	}
+}
+
+func TestParse(t *testing.T) {
+	var m = parse("@time=now;account=a\\sb :nick!user@host PRIVMSG #chan :hello :world  \r\n")
+	if m.tags["time"] != "now" || m.tags["account"] != "a b" {
+		t.Error("Unexpected tags", m.tags)
+	}
+	if m.prefix != "nick!user@host" || m.nick() != "nick" || m.command != "PRIVMSG" || m.param(0) != "#chan" || m.param(1) != "hello :world  " || m.param(2) != "" {
+		t.Error("Unexpected message", m)
+	}
+	if m = parse("ping srv"); m.command != "PING" || m.param(0) != "srv" || m.nick() != "" {
+		t.Error("Unexpected message", m)
+	}
+	if ls := split(strings.Repeat("é", maxText)); len(ls) != 2 || len(ls[0]) != maxText || ls[0]+ls[1] != strings.Repeat("é", maxText) {
+		t.Error("Unexpected split", len(ls))
+	}
+}
@@ -0,0 +1,311 @@
+/*
+Package matrix is a chat.Transport for a Matrix room, using the client-server API.
+
+Room messages are received by long polling /sync. Edits, and messages sent before the Transport started, are ignored.
+Private messages are sent in a direct chat room, created with the player the first time it is needed.
+
+Usage:
+
+	var t = matrix.New(matrix.Config{Homeserver: "https://matrix.example.org", Token: token, Room: "!room:example.org"})
+	var b = chat.NewBridge(t, engine.IncomingTalk)
+	go b.Run(ctx)
+*/
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// Config contains Matrix settings. Zero values are replaced by defaults.
+type Config struct {
+	// Homeserver is the base URL of the homeserver.
+	Homeserver string
+	// Token is the bot's access token.
+	Token string
+	// Room is the ID of the game room.
+	Room string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// PollTimeout is how long each sync waits for new messages.
+	PollTimeout time.Duration
+	// Failed syncs are retried after Backoff, doubling after each failure up to MaxBackoff.
+	Backoff, MaxBackoff time.Duration
+}
+
+// DefaultConfig contains the default settings for Config.
+var DefaultConfig = Config{
+	PollTimeout: 30 * time.Second,
+	Backoff:     time.Second,
+	MaxBackoff:  5 * time.Minute,
+}
+
+// Error is returned when the homeserver rejects a request.
+type Error struct {
+	Status  int
+	Code    string `json:"errcode"`
+	Message string `json:"error"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("matrix: %d %v: %v", e.Status, e.Code, e.Message)
+}
+
+// Transport is a connection to a Matrix room.
+type Transport struct {
+	cfg   Config
+	mu    sync.Mutex
+	names map[string]string
+	dms   map[string]string
+	txn   int
+}
+
+// New creates a new Transport instance with settings cfg.
+func New(cfg Config) *Transport {
+	var d = DefaultConfig
+	cfg.Homeserver = strings.TrimSuffix(cfg.Homeserver, "/")
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	if cfg.PollTimeout == 0 {
+		cfg.PollTimeout = d.PollTimeout
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = d.Backoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = d.MaxBackoff
+	}
+	return &Transport{cfg: cfg, names: make(map[string]string), dms: make(map[string]string)}
+}
+
+// do sends a request to the client-server API with a JSON body, decoding the JSON response into res.
+func (t *Transport) do(ctx context.Context, method, path string, q url.Values, body, res interface{}) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	var u = t.cfg.Homeserver + "/_matrix/client/v3/" + path
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	var resp, raw, err = chat.Retry(t.cfg.Client, func() (*http.Request, error) {
+		var req, err = http.NewRequestWithContext(ctx, method, u, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+t.cfg.Token)
+		return req, nil
+	}, func(resp *http.Response, body []byte) time.Duration {
+		var rl struct {
+			RetryAfter int64 `json:"retry_after_ms"`
+		}
+		json.Unmarshal(body, &rl)
+		return time.Duration(rl.RetryAfter) * time.Millisecond
+	})
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var e = &Error{Status: resp.StatusCode}
+		json.Unmarshal(raw, e)
+		return e
+	}
+	if res != nil {
+		return json.Unmarshal(raw, res)
+	}
+	return nil
+}
+
+// send a text message to room.
+func (t *Transport) send(room, text string) error {
+	t.mu.Lock()
+	t.txn++
+	var txn = strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.Itoa(t.txn)
+	t.mu.Unlock()
+	return t.do(context.Background(), "PUT", "rooms/"+url.PathEscape(room)+"/send/m.room.message/"+txn, nil,
+		map[string]string{"msgtype": "m.text", "body": text}, nil)
+}
+
+// Send text to the room.
+func (t *Transport) Send(text string) error {
+	return t.send(t.cfg.Room, text)
+}
+
+// SendPrivate sends text to user in a direct chat.
+func (t *Transport) SendPrivate(user, text string) error {
+	t.mu.Lock()
+	var room, ok = t.dms[user]
+	t.mu.Unlock()
+	if !ok {
+		var res struct {
+			RoomID string `json:"room_id"`
+		}
+		var err = t.do(context.Background(), "POST", "createRoom", nil, map[string]interface{}{
+			"invite":    []string{user},
+			"is_direct": true,
+			"preset":    "trusted_private_chat",
+		}, &res)
+		if err != nil {
+			return err
+		}
+		room = res.RoomID
+		t.mu.Lock()
+		t.dms[user] = room
+		t.mu.Unlock()
+	}
+	return t.send(room, text)
+}
+
+// name returns the display name of user, looking it up if needed.
+func (t *Transport) name(ctx context.Context, user string) string {
+	t.mu.Lock()
+	var n, ok = t.names[user]
+	t.mu.Unlock()
+	if ok {
+		return n
+	}
+	var res struct {
+		DisplayName string `json:"displayname"`
+	}
+	if err := t.do(ctx, "GET", "profile/"+url.PathEscape(user)+"/displayname", nil, nil, &res); err != nil {
+		// Nb. the name is looked up again next time.
+		return user
+	}
+	if n = res.DisplayName; n == "" {
+		n = user
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.names[user] = n
+	return n
+}
+
+// event is a room timeline event.
+type event struct {
+	Type    string `json:"type"`
+	Sender  string `json:"sender"`
+	TS      int64  `json:"origin_server_ts"`
+	Content struct {
+		MsgType    string          `json:"msgtype"`
+		Body       string          `json:"body"`
+		NewContent json.RawMessage `json:"m.new_content"`
+	} `json:"content"`
+}
+
+// syncResponse is the part of a /sync response holding the room's timeline.
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			Timeline struct {
+				Events []event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// sync the room's timeline since batch, waiting up to timeout for new events.
+func (t *Transport) sync(ctx context.Context, since string, timeout time.Duration) (*syncResponse, error) {
+	var f, _ = json.Marshal(map[string]interface{}{
+		"room": map[string]interface{}{
+			"rooms":    []string{t.cfg.Room},
+			"timeline": map[string]interface{}{"types": []string{"m.room.message"}},
+		},
+	})
+	var q = url.Values{"filter": {string(f)}, "timeout": {strconv.FormatInt(timeout.Milliseconds(), 10)}}
+	if since != "" {
+		q.Set("since", since)
+	}
+	var res syncResponse
+	return &res, t.do(ctx, "GET", "sync", q, nil, &res)
+}
+
+/*
+Run joins the room and passes messages said there to recv, until ctx is done.
+Failed syncs are retried, unless the access token is rejected.
+*/
+func (t *Transport) Run(ctx context.Context, recv func(m chat.Message)) error {
+	var who struct {
+		UserID string `json:"user_id"`
+	}
+	if err := t.do(ctx, "GET", "account/whoami", nil, nil, &who); err != nil {
+		return err
+	}
+	if err := t.do(ctx, "POST", "join/"+url.PathEscape(t.cfg.Room), nil, struct{}{}, nil); err != nil {
+		return err
+	}
+	var (
+		since string
+		wait  = t.cfg.Backoff
+	)
+	for since == "" {
+		// skip messages from before the start
+		var res, err = t.sync(ctx, "", 0)
+		if err == nil {
+			since = res.NextBatch
+		} else if err := t.retry(ctx, err, &wait); err != nil {
+			return err
+		}
+	}
+	for {
+		var res, err = t.sync(ctx, since, t.cfg.PollTimeout)
+		if err != nil {
+			if err := t.retry(ctx, err, &wait); err != nil {
+				return err
+			}
+			continue
+		}
+		wait = t.cfg.Backoff
+		since = res.NextBatch
+		for _, ev := range res.Rooms.Join[t.cfg.Room].Timeline.Events {
+			if ev.Type != "m.room.message" || ev.Sender == who.UserID || ev.Content.NewContent != nil {
+				continue
+			}
+			if ev.Content.MsgType != "m.text" && ev.Content.MsgType != "m.emote" {
+				continue
+			}
+			recv(chat.Message{
+				Room:    t.cfg.Room,
+				User:    ev.Sender,
+				Name:    t.name(ctx, ev.Sender),
+				Account: ev.Sender,
+				Time:    time.UnixMilli(ev.TS),
+				Text:    ev.Content.Body,
+			})
+		}
+	}
+}
+
+// retry after a failed request, waiting out the backoff. Returns an error if the failure cannot be retried.
+func (t *Transport) retry(ctx context.Context, err error, wait *time.Duration) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if e, ok := err.(*Error); ok && (e.Code == "M_UNKNOWN_TOKEN" || e.Code == "M_FORBIDDEN") {
+		return err
+	}
+	select {
+	case <-time.After(*wait):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if *wait *= 2; *wait > t.cfg.MaxBackoff {
+		*wait = t.cfg.MaxBackoff
+	}
+	return nil
+}
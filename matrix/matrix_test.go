@@ -0,0 +1,133 @@
+package matrix
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// fakeHomeserver serves a room with a fixed history, then the messages queued by say.
+type fakeHomeserver struct {
+	mu     sync.Mutex
+	joined bool
+	next   []map[string]interface{}
+	sent   map[string][]string
+}
+
+func message(sender, body string, edit bool) map[string]interface{} {
+	var c = map[string]interface{}{"msgtype": "m.text", "body": body}
+	if edit {
+		c["m.new_content"] = map[string]string{"msgtype": "m.text", "body": body}
+	}
+	return map[string]interface{}{"type": "m.room.message", "sender": sender, "origin_server_ts": 1700000000000, "content": c}
+}
+
+func (h *fakeHomeserver) say(evs ...map[string]interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.next = append(h.next, evs...)
+}
+
+func (h *fakeHomeserver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var reply = func(v interface{}) { json.NewEncoder(w).Encode(v) }
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		reply(map[string]string{"errcode": "M_UNKNOWN_TOKEN", "error": "Unknown token"})
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var path = strings.TrimPrefix(r.URL.EscapedPath(), "/_matrix/client/v3/")
+	switch {
+	case path == "account/whoami":
+		reply(map[string]string{"user_id": "@bot:example.org"})
+	case path == "join/%21room:example.org":
+		h.joined = true
+		reply(map[string]string{"room_id": "!room:example.org"})
+	case path == "sync":
+		var evs = []map[string]interface{}{message("@ace:example.org", "old history", false)}
+		if r.FormValue("since") != "" {
+			evs, h.next = h.next, nil
+		}
+		reply(map[string]interface{}{
+			"next_batch": "batch",
+			"rooms": map[string]interface{}{"join": map[string]interface{}{
+				"!room:example.org": map[string]interface{}{"timeline": map[string]interface{}{"events": evs}},
+			}},
+		})
+	case strings.HasPrefix(path, "profile/"):
+		reply(map[string]string{"displayname": "Ace"})
+	case path == "createRoom":
+		var req struct {
+			Invite []string `json:"invite"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		reply(map[string]string{"room_id": "!dm-" + req.Invite[0]})
+	case strings.HasPrefix(path, "rooms/") && r.Method == "PUT":
+		var room = strings.SplitN(strings.TrimPrefix(r.URL.Path, "/_matrix/client/v3/rooms/"), "/", 2)[0]
+		var m struct {
+			Body string `json:"body"`
+		}
+		json.NewDecoder(r.Body).Decode(&m)
+		h.sent[room] = append(h.sent[room], m.Body)
+		reply(map[string]string{"event_id": "$1"})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		reply(map[string]string{"errcode": "M_UNRECOGNIZED", "error": path})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var h = &fakeHomeserver{sent: make(map[string][]string)}
+	var srv = httptest.NewServer(h)
+	defer srv.Close()
+	var tr = New(Config{Homeserver: srv.URL + "/", Token: "token", Room: "!room:example.org", PollTimeout: time.Millisecond})
+	var recv = make(chan chat.Message, 10)
+	var ctx, cancel = context.WithCancel(context.Background())
+	var res = make(chan error)
+	go func() { res <- tr.Run(ctx, func(m chat.Message) { recv <- m }) }()
+	h.say(
+		message("@bot:example.org", "from the bot", false),
+		message("@ace:example.org", "* edited kw", true),
+		message("@ace:example.org", "hello", false),
+	)
+	select {
+	case m := <-recv:
+		if m.Text != "hello" || m.User != "@ace:example.org" || m.Name != "Ace" || m.Room != "!room:example.org" || m.Time.IsZero() {
+			t.Error("Unexpected message", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Message not received")
+	}
+	if len(recv) != 0 {
+		t.Error("Unexpected message", <-recv)
+	}
+	if err := tr.Send("The game has begun."); err != nil {
+		t.Error(err)
+	}
+	if err := tr.SendPrivate("@ace:example.org", "secret"); err != nil {
+		t.Error(err)
+	}
+	h.mu.Lock()
+	if !h.joined || len(h.sent["!room:example.org"]) != 1 || h.sent["!dm-@ace:example.org"][0] != "secret" {
+		t.Error("Unexpected messages", h.joined, h.sent)
+	}
+	h.mu.Unlock()
+	cancel()
+	if err := <-res; err != context.Canceled {
+		t.Error("Unexpected error", err)
+	}
+	t.Run("BadToken", func(t *testing.T) {
+		var err = New(Config{Homeserver: srv.URL, Token: "wrong", Room: "!room:example.org"}).Run(context.Background(), nil)
+		if e, ok := err.(*Error); !ok || e.Code != "M_UNKNOWN_TOKEN" {
+			t.Error("Unexpected error", err)
+		}
+	})
+}
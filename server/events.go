@@ -0,0 +1,353 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/internal/websocket"
+)
+
+// keepAlive is how often an idle event stream is sent a comment, to keep the connection open.
+var keepAlive = 30 * time.Second
+
+// player is the public JSON encoding of a player.
+type player struct {
+	ID   assassin.ID `json:"id"`
+	Name string      `json:"name"`
+}
+
+/*
+event is the JSON encoding of a game event sent to clients.
+Only events everyone may see are public; a player's new target and KillWord are sent to them alone.
+Attacks are kept secret, as they would give away who is targeting whom.
+Announcements are the public messages of a MessageRenderer.
+*/
+type event struct {
+	ID       int         `json:"-"`
+	Type     string      `json:"-"`
+	To       assassin.ID `json:"-"`
+	Time     time.Time   `json:"time"`
+	Text     string      `json:"text,omitempty"`
+	Players  []player    `json:"players,omitempty"`
+	Player   *player     `json:"player,omitempty"`
+	Alive    int         `json:"alive,omitempty"`
+	Target   *player     `json:"target,omitempty"`
+	KillWord string      `json:"killWord,omitempty"`
+	Winners  []player    `json:"winners,omitempty"`
+	Reason   string      `json:"reason,omitempty"`
+	Chain    []link      `json:"chain,omitempty"`
+}
+
+/*
+link is a player's place in the chains of targets revealed at the end of a game.
+Target is the player's first target, left out for survivors; By and Method tell how the player was eliminated.
+*/
+type link struct {
+	Player player  `json:"player"`
+	Target *player `json:"target,omitempty"`
+	By     *player `json:"by,omitempty"`
+	Method string  `json:"method,omitempty"`
+}
+
+// encode ev for clients, reporting whether it is passed on at all.
+func encode(ev assassin.Event) (event, bool) {
+	var e = event{Time: ev.At()}
+	switch ev := ev.(type) {
+	case assassin.GameStarted:
+		e.Type = "started"
+		for _, p := range ev.Players {
+			e.Players = append(e.Players, player{p.ID, p.Name})
+		}
+	case assassin.TargetAssigned:
+		e.Type, e.To = "target", ev.Player.ID
+		if ev.Player.KillWord != "" {
+			e.Target = &player{ev.Target.ID, ev.Target.Name}
+			e.KillWord = ev.Player.KillWord
+		}
+	case assassin.PlayerEliminated:
+		e.Type = "eliminated"
+		e.Player = &player{ev.Player.ID, ev.Player.Name}
+	case assassin.GameEnded:
+		e.Type = "ended"
+		for _, p := range ev.Winners {
+			e.Winners = append(e.Winners, player{p.ID, p.Name})
+		}
+		e.Reason = ev.Reason
+	default:
+		return e, false
+	}
+	return e, true
+}
+
+// write e to w in Server-Sent Events format.
+func (e event) write(w io.Writer) error {
+	var b, err = json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, b)
+	return err
+}
+
+// stream is a client following a game's events. Player is 0 for spectators.
+type stream struct {
+	player assassin.ID
+	c      chan event
+}
+
+// wants reports whether e may be sent to the stream.
+func (st *stream) wants(e event) bool {
+	return e.To == 0 || e.To == st.player
+}
+
+// follower is the Subscriber following the events of a running game.
+type follower struct {
+	s  *Server
+	gm *game
+	r  *assassin.MessageRenderer
+}
+
+func newFollower(s *Server, gm *game) *follower {
+	return &follower{s, gm, assassin.NewMessageRenderer(s.tpl, announcer{gm})}
+}
+
+func (f *follower) Handle(ev assassin.Event) {
+	var s, gm = f.s, f.gm
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch ev := ev.(type) {
+	case assassin.GameStarted:
+		close(gm.started)
+	case assassin.TargetAssigned:
+		if _, ok := gm.first[ev.Player.ID]; !ok {
+			gm.first[ev.Player.ID] = ev.Target.ID
+		}
+		gm.targets[ev.Player.ID] = ev
+	case assassin.PlayerEliminated:
+		gm.alive[ev.Player.ID] = false
+		gm.kills[ev.Player.ID] = ev
+	case assassin.GameEnded:
+		gm.winners = ev.Winners
+		gm.reason = ev.Reason
+	}
+	var e, ok = encode(ev)
+	if !ok {
+		return
+	}
+	switch e.Type {
+	case "started", "eliminated":
+		e.Alive = gm.count()
+	}
+	gm.publish(e)
+	f.r.Handle(ev)
+	if e.Type == "ended" && s.reveal {
+		gm.publish(event{Type: "reveal", Time: e.Time, Chain: gm.chain()})
+	}
+}
+
+// announcer is the MessageHandler publishing a game's announcements. Notifications are private, so dropped.
+type announcer struct {
+	gm *game
+}
+
+func (a announcer) Announce(s string) {
+	a.gm.publish(event{Type: "announce", Time: time.Now(), Text: s})
+}
+
+func (a announcer) Notify(p assassin.Player, s string) {}
+
+// count returns how many players are alive. The caller must hold s.mu.
+func (gm *game) count() int {
+	var n int
+	for _, a := range gm.alive {
+		if a {
+			n++
+		}
+	}
+	return n
+}
+
+// publish e to the game's streams. The caller must hold s.mu.
+func (gm *game) publish(e event) {
+	e.ID = len(gm.events) + 1
+	gm.events = append(gm.events, e)
+	for st := range gm.streams {
+		if !st.wants(e) {
+			continue
+		}
+		select {
+		case st.c <- e:
+		default:
+			// Nb. the game does not wait for slow clients; they can reconnect to catch up.
+			close(st.c)
+			delete(gm.streams, st)
+		}
+	}
+}
+
+/*
+chain returns every player's place in the game's starting chains of targets, in order of player ID,
+so the order gives nothing away. The caller must hold s.mu.
+*/
+func (gm *game) chain() []link {
+	if len(gm.first) == 0 {
+		return nil
+	}
+	var ls = make([]link, 0, len(gm.names))
+	for _, id := range sortedIDs(gm.names) {
+		var l = link{Player: player{id, gm.names[id]}}
+		if t, ok := gm.first[id]; ok && !gm.alive[id] {
+			l.Target = &player{t, gm.names[t]}
+		}
+		if k, ok := gm.kills[id]; ok {
+			l.By = &player{k.By.ID, k.By.Name}
+			l.Method = k.Method.String()
+		}
+		ls = append(ls, l)
+	}
+	return ls
+}
+
+/*
+follow adds st to the game's streams, unless the game is over.
+Returns the events already published after event number last, that st wants.
+*/
+func (s *Server) follow(gm *game, st *stream, last int) []event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var backlog []event
+	for _, e := range gm.events {
+		if e.ID > last && st.wants(e) {
+			backlog = append(backlog, e)
+		}
+	}
+	if gm.state == over {
+		close(st.c)
+	} else {
+		gm.streams[st] = true
+	}
+	return backlog
+}
+
+// unfollow removes st from the game's streams.
+func (s *Server) unfollow(gm *game, st *stream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(gm.streams, st)
+}
+
+/*
+events streams the game's events as Server-Sent Events, until the game is over.
+Events already published are sent first, after the one given by a Last-Event-ID header.
+*/
+func (s *Server) events(w http.ResponseWriter, r *http.Request, gm *game) {
+	var fl, ok = w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	var st = &stream{c: make(chan event, 64)}
+	if token(r) != "" {
+		if st.player = s.player(gm, r); st.player == 0 {
+			writeError(w, http.StatusUnauthorized, "invalid player token")
+			return
+		}
+	}
+	var last, _ = strconv.Atoi(r.Header.Get("Last-Event-ID"))
+	var backlog = s.follow(gm, st, last)
+	defer s.unfollow(gm, st)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, e := range backlog {
+		e.write(w)
+	}
+	fl.Flush()
+	var ping = time.NewTicker(keepAlive)
+	defer ping.Stop()
+	for {
+		select {
+		case e, ok := <-st.c:
+			if !ok {
+				return
+			}
+			if e.write(w) != nil {
+				return
+			}
+		case <-ping.C:
+			io.WriteString(w, ": ping\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		fl.Flush()
+	}
+}
+
+// sortedIDs returns the IDs of the players named, in ascending order.
+func sortedIDs(names map[assassin.ID]string) []assassin.ID {
+	var ids = make([]assassin.ID, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// message is the WebSocket encoding of an event.
+type message struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"`
+	event
+}
+
+/*
+watch streams the game's public events to a spectator over a WebSocket, as JSON messages, until the game is over.
+Events already published are sent first, after event number last if given in the query.
+*/
+func (s *Server) watch(w http.ResponseWriter, r *http.Request, gm *game) {
+	var last, _ = strconv.Atoi(r.URL.Query().Get("last"))
+	var c, err = websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	var st = &stream{c: make(chan event, 64)}
+	var backlog = s.follow(gm, st, last)
+	defer s.unfollow(gm, st)
+	var gone = make(chan struct{})
+	go func() {
+		// Nb. spectators have nothing to say; reading only answers pings and notices them leave.
+		defer close(gone)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	for _, e := range backlog {
+		if c.WriteJSON(message{e.ID, e.Type, e}) != nil {
+			c.Close(websocket.CloseGoingAway)
+			return
+		}
+	}
+	for {
+		select {
+		case e, ok := <-st.c:
+			if !ok {
+				c.Close(websocket.CloseNormal)
+				return
+			}
+			if c.WriteJSON(message{e.ID, e.Type, e}) != nil {
+				c.Close(websocket.CloseGoingAway)
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
@@ -0,0 +1,520 @@
+/*
+Package server runs assassin games behind an HTTP/JSON API, for web front ends.
+
+A game is created with a list of player names and KillWords. Each player is handed a secret token,
+used to post their talk and look up their target, and the creator is handed an admin token to start and stop the game.
+Tokens are sent as "Authorization: Bearer <token>", or in a token query parameter where headers cannot be set.
+
+	POST /games               create a game: {"players": ["Ace", "Bee"], "words": ["apple", "pear"]},
+	                          with optional "match": {"stem": true, "leet": true} (see assassin.WordMatcher),
+	                          and "teams": ["red", "blue"] naming each player's team for a team game
+	GET  /games/{id}          public status: players, how many are alive, and the winners once over
+	POST /games/{id}/start    start the game (admin)
+	POST /games/{id}/stop     stop the game early, with an optional {"reason": "..."} (admin)
+	POST /games/{id}/talk     say something in the game: {"text": "..."} (player)
+	GET  /games/{id}/target   the player's target and KillWord (player)
+	GET  /games/{id}/events   game events as Server-Sent Events, with the player's own events if authenticated
+	GET  /games/{id}/watch    public game events over a WebSocket, for spectators
+
+Public events are announcements, eliminations with the count of players still alive, and the end of the game.
+With SetReveal, the chain of targets is also revealed once the game is over.
+KillWords and targets are never made public.
+
+Errors are reported as {"error": "..."}, with a matching HTTP status.
+Games are forgotten an hour after they are over.
+
+Usage:
+
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, timing, nil)
+	log.Fatal(http.ListenAndServe(":8080", server.NewServer(e, assassin.LangEn)))
+*/
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// state is the stage a game has reached.
+type state int
+
+const (
+	created state = iota
+	running
+	over
+)
+
+func (s state) String() string {
+	switch s {
+	case created:
+		return "created"
+	case running:
+		return "running"
+	}
+	return "over"
+}
+
+/*
+game holds a game created through the API.
+While the game runs, its public state is followed from engine events rather than read from g.
+*/
+type game struct {
+	g       *assassin.Game
+	names   map[assassin.ID]string
+	admin   string
+	tokens  map[string]assassin.ID
+	state   state
+	alive   map[assassin.ID]bool
+	targets map[assassin.ID]assassin.TargetAssigned
+	first   map[assassin.ID]assassin.ID
+	kills   map[assassin.ID]assassin.PlayerEliminated
+	winners []assassin.Player
+	reason  string
+	events  []event
+	streams map[*stream]bool
+	started chan struct{}
+	done    chan struct{}
+}
+
+// Server is an http.Handler running games on a GameEngine.
+type Server struct {
+	e      *assassin.GameEngine
+	tpl    assassin.Lang
+	mu     sync.Mutex
+	games  map[assassin.ID]*game
+	reveal bool
+}
+
+// NewServer creates a new Server instance running games on e, with error messages from tpl.
+func NewServer(e *assassin.GameEngine, tpl assassin.Lang) *Server {
+	return &Server{e: e, tpl: tpl, games: make(map[assassin.ID]*game)}
+}
+
+/*
+SetReveal sets whether the chain of targets is revealed to everyone once a game is over.
+The onward links of players who survived are left out.
+*/
+func (s *Server) SetReveal(reveal bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reveal = reveal
+}
+
+// newToken returns a random secret.
+func newToken() string {
+	var b = make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// token returns the token sent with r, if any.
+func token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimPrefix(h, "Bearer ")
+	}
+	return r.URL.Query().Get("token")
+}
+
+// isAdmin reports whether r carries the admin token of gm.
+func (s *Server) isAdmin(gm *game, r *http.Request) bool {
+	return subtle.ConstantTimeCompare([]byte(token(r)), []byte(gm.admin)) == 1
+}
+
+// player returns the player whose token r carries, or 0 if none.
+func (s *Server) player(gm *game, r *http.Request) assassin.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return gm.tokens[token(r)]
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// readJSON decodes the request body into v. An empty body leaves v unchanged.
+func readJSON(r *http.Request, v interface{}) error {
+	var err = json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// ServeHTTP routes API requests.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var path = strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if path[0] != "games" || len(path) > 3 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if len(path) == 1 {
+		if allow(w, r, "POST") {
+			s.create(w, r)
+		}
+		return
+	}
+	var id, err = strconv.Atoi(path[1])
+	s.mu.Lock()
+	var gm = s.games[assassin.ID(id)]
+	s.mu.Unlock()
+	if err != nil || gm == nil {
+		writeError(w, http.StatusNotFound, "game not found")
+		return
+	}
+	var action string
+	if len(path) == 3 {
+		action = path[2]
+	}
+	var method = map[string]string{
+		"": "GET", "start": "POST", "stop": "POST", "talk": "POST", "target": "GET", "events": "GET", "watch": "GET",
+	}
+	if m, ok := method[action]; !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	} else if !allow(w, r, m) {
+		return
+	}
+	switch action {
+	case "start", "stop":
+		if !s.isAdmin(gm, r) {
+			writeError(w, http.StatusUnauthorized, s.tpl.Fmt(s.tpl.CA, action))
+			return
+		}
+	case "talk", "target":
+		if s.player(gm, r) == 0 {
+			writeError(w, http.StatusUnauthorized, "invalid player token")
+			return
+		}
+	}
+	switch action {
+	case "":
+		writeJSON(w, http.StatusOK, s.status(gm))
+	case "start":
+		s.start(w, r, gm)
+	case "stop":
+		s.stop(w, r, gm)
+	case "talk":
+		s.talk(w, r, gm)
+	case "target":
+		s.target(w, r, gm)
+	case "events":
+		s.events(w, r, gm)
+	case "watch":
+		s.watch(w, r, gm)
+	}
+}
+
+// allow reports whether r uses method, replying with an error if not.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+	return true
+}
+
+// createRequest is the body of a request to create a game.
+type createRequest struct {
+	Players []string `json:"players"`
+	Words   []string `json:"words"`
+	// Match sets how KillWords are matched, by default as whole words ignoring case and accents.
+	Match *assassin.WordMatcher `json:"match,omitempty"`
+	// Teams, if given, names the team of each player in turn, for a team game.
+	Teams []string `json:"teams,omitempty"`
+}
+
+// playerToken is a player created, along with their secret token.
+type playerToken struct {
+	player
+	Token string `json:"token"`
+}
+
+// createResponse is the reply to a request to create a game.
+type createResponse struct {
+	ID      assassin.ID   `json:"id"`
+	Token   string        `json:"token"`
+	Players []playerToken `json:"players"`
+}
+
+/*
+create a game from a list of player names and KillWords.
+Players are given IDs in the order listed. At least as many words as players are needed.
+*/
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req createRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Players) < assassin.MinPlayers {
+		writeError(w, http.StatusBadRequest, s.tpl.Fmt(s.tpl.CFP, assassin.MinPlayers))
+		return
+	}
+	var seen = make(map[string]bool, len(req.Players))
+	for _, n := range req.Players {
+		if n == "" || seen[n] {
+			writeError(w, http.StatusBadRequest, "player names must be unique and not empty")
+			return
+		}
+		seen[n] = true
+	}
+	var words = make([]string, 0, len(req.Words))
+	for _, kw := range req.Words {
+		if kw = strings.TrimSpace(kw); kw != "" {
+			words = append(words, kw)
+		}
+	}
+	if len(words) < len(req.Players) {
+		writeError(w, http.StatusBadRequest, "at least one word per player is needed")
+		return
+	}
+	var teams assassin.Teams
+	if len(req.Teams) > 0 {
+		teams = make(assassin.Teams, len(req.Teams))
+		var ids = make([]assassin.ID, 0, len(req.Teams))
+		for i, t := range req.Teams {
+			teams[assassin.ID(i+1)] = t
+			ids = append(ids, assassin.ID(i+1))
+		}
+		if len(req.Teams) != len(req.Players) || teams.Over(ids) {
+			writeError(w, http.StatusBadRequest, "teams must name the team of every player, with at least two teams")
+			return
+		}
+	}
+	var gm = &game{
+		names:   make(map[assassin.ID]string, len(req.Players)),
+		admin:   newToken(),
+		tokens:  make(map[string]assassin.ID, len(req.Players)),
+		alive:   make(map[assassin.ID]bool, len(req.Players)),
+		targets: make(map[assassin.ID]assassin.TargetAssigned, len(req.Players)),
+		first:   make(map[assassin.ID]assassin.ID, len(req.Players)),
+		kills:   make(map[assassin.ID]assassin.PlayerEliminated, len(req.Players)),
+		streams: make(map[*stream]bool),
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	var res = createResponse{Token: gm.admin, Players: make([]playerToken, 0, len(req.Players))}
+	for i, n := range req.Players {
+		var id = assassin.ID(i + 1)
+		var t = newToken()
+		gm.names[id] = n
+		gm.tokens[t] = id
+		gm.alive[id] = true
+		res.Players = append(res.Players, playerToken{player{id, n}, t})
+	}
+	s.mu.Lock()
+	res.ID = s.e.NewID()
+	gm.g = assassin.NewGame(res.ID, gm.names, assassin.NewWordList(words, nil), nil)
+	if req.Match != nil {
+		gm.g.SetMatcher(*req.Match)
+	}
+	if teams != nil {
+		gm.g.SetTargetAssigner(teams)
+	}
+	s.games[res.ID] = gm
+	s.mu.Unlock()
+	w.Header().Set("Location", "/games/"+strconv.Itoa(int(res.ID)))
+	writeJSON(w, http.StatusCreated, res)
+}
+
+// playerStatus is the public state of a player.
+type playerStatus struct {
+	player
+	Alive bool `json:"alive"`
+}
+
+// statusResponse is the public state of a game.
+type statusResponse struct {
+	ID      assassin.ID    `json:"id"`
+	State   string         `json:"state"`
+	Alive   int            `json:"alive"`
+	Players []playerStatus `json:"players"`
+	Winners []player       `json:"winners,omitempty"`
+	Reason  string         `json:"reason,omitempty"`
+}
+
+// status returns the public state of gm.
+func (s *Server) status(gm *game) statusResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res = statusResponse{ID: gm.g.ID, State: gm.state.String(), Reason: gm.reason}
+	for _, id := range sortedIDs(gm.names) {
+		res.Players = append(res.Players, playerStatus{player{id, gm.names[id]}, gm.alive[id]})
+		if gm.alive[id] {
+			res.Alive++
+		}
+	}
+	if gm.state != running {
+		// Nb. the game is only safe to read while the engine is not running it.
+		res.Alive = gm.g.Status()
+	}
+	for _, p := range gm.winners {
+		res.Winners = append(res.Winners, player{p.ID, p.Name})
+	}
+	return res
+}
+
+/*
+start the game on the engine, replying once it has begun.
+The game's events are followed until it is over.
+*/
+func (s *Server) start(w http.ResponseWriter, r *http.Request, gm *game) {
+	s.mu.Lock()
+	if gm.state != created {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, s.tpl.EIP)
+		return
+	}
+	gm.state = running
+	s.mu.Unlock()
+	var f = assassin.ForGame(gm.g.ID, newFollower(s, gm))
+	s.e.Subscribe(f)
+	go func() {
+		var _, err = s.e.Run(gm.g)
+		s.e.Unsubscribe(f)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if err != nil {
+			gm.reason = err.Error()
+		}
+		s.end(gm)
+	}()
+	select {
+	case <-gm.started:
+	case <-gm.done:
+	}
+	writeJSON(w, http.StatusOK, s.status(gm))
+}
+
+// evictAfter is how long a game is kept once it is over, for clients to catch up on its result.
+var evictAfter = time.Hour
+
+/*
+end marks gm as over, closing its event streams, and removes it from the server once evictAfter has passed.
+The caller must hold s.mu.
+*/
+func (s *Server) end(gm *game) {
+	gm.state = over
+	for st := range gm.streams {
+		close(st.c)
+	}
+	gm.streams = nil
+	close(gm.done)
+	time.AfterFunc(evictAfter, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.games, gm.g.ID)
+	})
+}
+
+// stopRequest is the body of a request to stop a game.
+type stopRequest struct {
+	Reason string `json:"reason"`
+}
+
+/*
+stop the game early, replying once it is over.
+A game that has not started is ended straight away.
+*/
+func (s *Server) stop(w http.ResponseWriter, r *http.Request, gm *game) {
+	var req stopRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	var st = gm.state
+	if st == created {
+		if gm.reason = req.Reason; gm.reason == "" {
+			gm.reason = s.tpl.GQR
+		}
+		s.end(gm)
+	}
+	s.mu.Unlock()
+	switch st {
+	case running:
+		if err := s.e.Stop(gm.g.ID, req.Reason); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+	case over:
+		writeError(w, http.StatusConflict, s.tpl.ENR)
+		return
+	}
+	select {
+	case <-gm.done:
+	case <-r.Context().Done():
+		return
+	}
+	writeJSON(w, http.StatusOK, s.status(gm))
+}
+
+// talkRequest is the body of a request to say something in a game.
+type talkRequest struct {
+	Text string `json:"text"`
+}
+
+// talk passes the player's text to the running game. Talk before the engine has started the game is refused.
+func (s *Server) talk(w http.ResponseWriter, r *http.Request, gm *game) {
+	var req talkRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var id = s.player(gm, r)
+	s.mu.Lock()
+	var st = gm.state
+	s.mu.Unlock()
+	select {
+	case <-gm.started:
+	default:
+		st = created
+	}
+	if st != running {
+		writeError(w, http.StatusConflict, s.tpl.ENR)
+		return
+	}
+	s.e.IncomingTalk(assassin.Talk{Game: gm.g.ID, Speaker: id, Name: gm.names[id], Time: time.Now(), Text: req.Text})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// targetResponse is a player's private state.
+type targetResponse struct {
+	Alive    bool    `json:"alive"`
+	Target   *player `json:"target,omitempty"`
+	KillWord string  `json:"killWord,omitempty"`
+}
+
+// target replies with the player's current target and KillWord.
+func (s *Server) target(w http.ResponseWriter, r *http.Request, gm *game) {
+	var id = s.player(gm, r)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if gm.state == created {
+		writeError(w, http.StatusConflict, s.tpl.ENR)
+		return
+	}
+	var res = targetResponse{Alive: gm.alive[id]}
+	if a := gm.targets[id]; res.Alive && a.Player.KillWord != "" {
+		res.Target = &player{a.Target.ID, a.Target.Name}
+		res.KillWord = a.Player.KillWord
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/internal/websocket"
+)
+
+type timing time.Duration
+
+func (t timing) Calc() time.Duration { return time.Duration(t) }
+
+// client sends API requests to a test server.
+type client struct {
+	t   *testing.T
+	url string
+}
+
+// do sends a request with an optional JSON body and token, decoding the JSON reply into res.
+func (c client) do(method, path, token string, body, res interface{}) int {
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	var req, _ = http.NewRequest(method, c.url+path, bytes.NewReader(b))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	var resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if res != nil {
+		json.NewDecoder(resp.Body).Decode(res)
+	}
+	return resp.StatusCode
+}
+
+// sse is a single Server-Sent Event.
+type sse struct {
+	ID, Type string
+	Data     event
+}
+
+// readEvent reads the next event from a stream, skipping comments.
+func readEvent(r *bufio.Reader) (sse, error) {
+	var e sse
+	for {
+		var line, err = r.ReadString('\n')
+		if err != nil {
+			return e, err
+		}
+		switch line = strings.TrimSuffix(line, "\n"); {
+		case line == "" && e.Type != "":
+			return e, nil
+		case strings.HasPrefix(line, "id: "):
+			e.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.Type = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.Data)
+		}
+	}
+}
+
+// readEvents reads a stream to the end, returning the types of events sent.
+func readEvents(r io.Reader) ([]string, []sse) {
+	var br = bufio.NewReader(r)
+	var ts []string
+	var es []sse
+	for {
+		var e, err = readEvent(br)
+		if err != nil {
+			return ts, es
+		}
+		ts = append(ts, e.Type)
+		es = append(es, e)
+	}
+}
+
+func TestServer(t *testing.T) {
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, timing(time.Hour), nil)
+	var srv = httptest.NewServer(NewServer(e, assassin.LangEn))
+	defer srv.Close()
+	var c = client{t, srv.URL}
+	var g createResponse
+	t.Run("Create", func(t *testing.T) {
+		if s := c.do("POST", "/games", "", createRequest{Players: []string{"Ace"}, Words: []string{"a", "b"}}, nil); s != http.StatusBadRequest {
+			t.Error("Unexpected status", s)
+		}
+		if s := c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee"}, Words: []string{"a", " "}}, nil); s != http.StatusBadRequest {
+			t.Error("Unexpected status", s)
+		}
+		if s := c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Ace"}, Words: []string{"a", "b"}}, nil); s != http.StatusBadRequest {
+			t.Error("Unexpected status", s)
+		}
+		if s := c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee"}, Words: []string{"a", "b"}, Teams: []string{"red", "red"}}, nil); s != http.StatusBadRequest {
+			t.Error("Unexpected status", s)
+		}
+		var s = c.do("POST", "/games", "", createRequest{
+			Players: []string{"Ace", "Bee", "Cat"},
+			Words:   []string{"apple", "banana", "cherry", "damson"},
+		}, &g)
+		if s != http.StatusCreated || g.ID != 1 || g.Token == "" || len(g.Players) != 3 || g.Players[1].Name != "Bee" || g.Players[1].Token == "" {
+			t.Fatal("Unexpected game", s, g)
+		}
+		if s := c.do("GET", "/games", "", nil, nil); s != http.StatusMethodNotAllowed {
+			t.Error("Unexpected status", s)
+		}
+		if s := c.do("GET", "/games/2", "", nil, nil); s != http.StatusNotFound {
+			t.Error("Unexpected status", s)
+		}
+	})
+	var (
+		path   = "/games/1"
+		tokens = make(map[assassin.ID]string)
+		status statusResponse
+	)
+	for _, p := range g.Players {
+		tokens[p.ID] = p.Token
+	}
+	if c.do("GET", path, "", nil, &status); status.State != "created" || status.Alive != 3 || len(status.Players) != 3 {
+		t.Error("Unexpected status", status)
+	}
+	if s := c.do("GET", path+"/target", tokens[1], nil, nil); s != http.StatusConflict {
+		t.Error("Unexpected status", s)
+	}
+	if s := c.do("POST", path+"/start", tokens[1], nil, nil); s != http.StatusUnauthorized {
+		t.Error("Unexpected status", s)
+	}
+	if s := c.do("POST", path+"/start", g.Token, nil, &status); s != http.StatusOK || status.State != "running" {
+		t.Fatal("Unexpected status", s, status)
+	}
+	if s := c.do("POST", path+"/start", g.Token, nil, nil); s != http.StatusConflict {
+		t.Error("Unexpected status", s)
+	}
+	var resp, err = http.Get(srv.URL + path + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var live = bufio.NewReader(resp.Body)
+	if ev, _ := readEvent(live); ev.Type != "started" || ev.ID != "1" || len(ev.Data.Players) != 3 || ev.Data.Alive != 3 {
+		t.Error("Unexpected event", ev)
+	}
+	if ev, _ := readEvent(live); ev.Type != "announce" || ev.Data.Text != assassin.LangEn.GS {
+		t.Error("Unexpected event", ev)
+	}
+	t.Run("Talk", func(t *testing.T) {
+		var a, b targetResponse
+		if s := c.do("GET", path+"/target", "wrong", nil, &a); s != http.StatusUnauthorized {
+			t.Error("Unexpected status", s)
+		}
+		if c.do("GET", path+"/target", tokens[1], nil, &a); !a.Alive || a.Target == nil || a.KillWord == "" {
+			t.Fatal("Unexpected target", a)
+		}
+		// Ace's target says Ace's KillWord
+		var victim = a.Target.ID
+		c.do("GET", path+"/target", tokens[victim], nil, &b)
+		if s := c.do("POST", path+"/talk", tokens[victim], talkRequest{"I like " + a.KillWord}, nil); s != http.StatusNoContent {
+			t.Error("Unexpected status", s)
+		}
+		if ev, _ := readEvent(live); ev.Type != "eliminated" || ev.Data.Player.ID != victim || ev.Data.Alive != 2 {
+			t.Error("Unexpected event", ev)
+		}
+		if ev, _ := readEvent(live); ev.Type != "announce" || ev.Data.Text != assassin.LangEn.Fmt(assassin.LangEn.GD, g.Players[victim-1].Name) {
+			t.Error("Unexpected event", ev)
+		}
+		if c.do("GET", path, "", nil, &status); status.Alive != 2 || status.Players[victim-1].Alive {
+			t.Error("Unexpected status", status)
+		}
+		var n targetResponse
+		if c.do("GET", path+"/target", tokens[1], nil, &n); n.Target == nil || n.Target.ID != b.Target.ID {
+			t.Error("Unexpected target", n, b)
+		}
+		var d targetResponse
+		if c.do("GET", path+"/target", tokens[victim], nil, &d); d.Alive || d.Target != nil {
+			t.Error("Unexpected target", d)
+		}
+	})
+	t.Run("Stop", func(t *testing.T) {
+		if s := c.do("POST", path+"/stop", g.Token, stopRequest{"time is up"}, &status); s != http.StatusOK {
+			t.Error("Unexpected status", s)
+		}
+		if status.State != "over" || status.Reason != "time is up" || len(status.Winners) != 2 || status.Alive != 2 {
+			t.Error("Unexpected status", status)
+		}
+		if ts, _ := readEvents(live); strings.Join(ts, " ") != "ended announce announce" {
+			t.Error("Unexpected events", ts)
+		}
+		if s := c.do("POST", path+"/talk", tokens[1], talkRequest{"hello"}, nil); s != http.StatusConflict {
+			t.Error("Unexpected status", s)
+		}
+		if s := c.do("POST", path+"/stop", g.Token, nil, nil); s != http.StatusConflict {
+			t.Error("Unexpected status", s)
+		}
+	})
+	t.Run("Replay", func(t *testing.T) {
+		var req, _ = http.NewRequest("GET", srv.URL+path+"/events?token="+tokens[1], nil)
+		req.Header.Set("Last-Event-ID", "1")
+		var resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var ts, es = readEvents(resp.Body)
+		if strings.Join(ts, " ") != "announce target eliminated announce target ended announce announce" {
+			t.Error("Unexpected events", ts)
+		}
+		if len(es) == 8 && (es[1].Data.KillWord == "" || es[4].Data.KillWord == es[1].Data.KillWord) {
+			t.Error("Unexpected targets", es)
+		}
+	})
+	t.Run("StopUnstarted", func(t *testing.T) {
+		var g createResponse
+		c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee"}, Words: []string{"a", "b"}}, &g)
+		if s := c.do("POST", "/games/2/stop", g.Token, nil, &status); s != http.StatusOK || status.State != "over" || status.Reason == "" {
+			t.Error("Unexpected status", s, status)
+		}
+		if s := c.do("POST", "/games/2/start", g.Token, nil, nil); s != http.StatusConflict {
+			t.Error("Unexpected status", s)
+		}
+	})
+}
+
+func TestWatch(t *testing.T) {
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, timing(time.Hour), nil)
+	var s = NewServer(e, assassin.LangEn)
+	s.SetReveal(true)
+	var srv = httptest.NewServer(s)
+	defer srv.Close()
+	var c = client{t, srv.URL}
+	var g createResponse
+	var words = []string{"apple", "banana", "cherry", "damson"}
+	c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee", "Cat"}, Words: words}, &g)
+	c.do("POST", "/games/1/start", g.Token, nil, nil)
+	var ws, err = websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/games/1/watch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var a targetResponse
+	c.do("GET", "/games/1/target", g.Players[0].Token, nil, &a)
+	var victim = a.Target.ID
+	c.do("POST", "/games/1/talk", g.Players[victim-1].Token, talkRequest{"I like " + a.KillWord}, nil)
+	var msgs []message
+	var raw []string
+	for {
+		var _, p, err = ws.ReadMessage()
+		if err != nil {
+			if ce, ok := err.(*websocket.CloseError); !ok || ce.Code != websocket.CloseNormal {
+				t.Error("Unexpected close", err)
+			}
+			break
+		}
+		var m message
+		json.Unmarshal(p, &m)
+		msgs = append(msgs, m)
+		raw = append(raw, string(p))
+		if m.Type == "eliminated" {
+			c.do("POST", "/games/1/stop", g.Token, stopRequest{"time is up"}, nil)
+		}
+	}
+	var ts []string
+	var last int
+	for _, m := range msgs {
+		// private events leave gaps in the IDs
+		if m.ID <= last {
+			t.Error("Unexpected message ID", m.ID, last)
+		}
+		last = m.ID
+		ts = append(ts, m.Type)
+	}
+	if strings.Join(ts, " ") != "started announce eliminated announce ended announce announce reveal" {
+		t.Error("Unexpected messages", ts)
+	}
+	for _, r := range raw {
+		for _, kw := range words {
+			if strings.Contains(r, kw) {
+				t.Error("KillWord leaked", r)
+			}
+		}
+	}
+	if len(msgs) != 8 {
+		t.FailNow()
+	}
+	var chain = msgs[7].Chain
+	if len(chain) != 3 || chain[0].Player.ID != 1 || chain[0].Target != nil {
+		t.Fatal("Unexpected chain", chain)
+	}
+	for i, l := range chain {
+		if l.Player.ID != assassin.ID(i+1) {
+			t.Error("Chain out of order", chain)
+		}
+		if dead := l.Player.ID == victim; dead != (l.Target != nil) || dead != (l.By != nil) {
+			t.Error("Unexpected link", l)
+		} else if dead && (l.By.ID != 1 || l.Method != "assassination") {
+			t.Error("Unexpected link", l)
+		}
+	}
+}
+
+func TestChain(t *testing.T) {
+	// two chains, as in a team game: 1 and 3 hunt each other, as do 2 and 4
+	var gm = &game{
+		names: map[assassin.ID]string{1: "Ace", 2: "Bee", 3: "Cat", 4: "Dog"},
+		first: map[assassin.ID]assassin.ID{1: 3, 3: 1, 2: 4, 4: 2},
+		alive: map[assassin.ID]bool{1: true, 2: false, 3: false, 4: true},
+		kills: map[assassin.ID]assassin.PlayerEliminated{},
+	}
+	var chain = gm.chain()
+	if len(chain) != 4 {
+		t.Fatal("Unexpected chain", chain)
+	}
+	for i, l := range chain {
+		var id = assassin.ID(i + 1)
+		if l.Player.ID != id || gm.alive[id] != (l.Target == nil) || l.Target != nil && l.Target.ID != gm.first[id] {
+			t.Error("Unexpected link", l)
+		}
+	}
+}
+
+func TestEvict(t *testing.T) {
+	defer func(d time.Duration) { evictAfter = d }(evictAfter)
+	evictAfter = 0
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, timing(time.Hour), nil)
+	var srv = httptest.NewServer(NewServer(e, assassin.LangEn))
+	defer srv.Close()
+	var c = client{t, srv.URL}
+	var g createResponse
+	c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee"}, Words: []string{"a", "b"}}, &g)
+	c.do("POST", "/games/1/stop", g.Token, nil, nil)
+	var deadline = time.Now().Add(time.Second)
+	for c.do("GET", "/games/1", "", nil, nil) != http.StatusNotFound {
+		if time.Now().After(deadline) {
+			t.Fatal("Finished game not evicted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTalkBeforeStart(t *testing.T) {
+	var e = assassin.NewGameEngine(assassin.LangEn, nil, timing(time.Hour), nil)
+	var s = NewServer(e, assassin.LangEn)
+	var srv = httptest.NewServer(s)
+	defer srv.Close()
+	var c = client{t, srv.URL}
+	var g createResponse
+	c.do("POST", "/games", "", createRequest{Players: []string{"Ace", "Bee"}, Words: []string{"a", "b"}}, &g)
+	if st := c.do("POST", "/games/1/talk", g.Players[0].Token, talkRequest{"hello"}, nil); st != http.StatusConflict {
+		t.Error("Unexpected status", st)
+	}
+	// starting, but not yet registered with the engine
+	s.mu.Lock()
+	s.games[g.ID].state = running
+	s.mu.Unlock()
+	if st := c.do("POST", "/games/1/talk", g.Players[0].Token, talkRequest{"hello"}, nil); st != http.StatusConflict {
+		t.Error("Unexpected status", st)
+	}
+}
@@ -0,0 +1,344 @@
+/*
+Package slack is a chat.Transport for a Slack channel.
+
+Messages are posted to the game channel with chat.postMessage, and private messages are sent by direct message.
+The Bot is also an http.Handler for the Events API, receiving the messages posted in the channel.
+It serves the Events API on Config.Listen while running, or can be mounted on another server.
+
+Usage:
+
+	var b = slack.NewBot(slack.Config{Token: token, SigningSecret: secret, Channel: "C0123456", Listen: ":8080"})
+	var br = chat.NewBridge(b, engine.IncomingTalk)
+	go br.Run(ctx)
+
+See package slacktest for a fake Slack API server to test against.
+*/
+package slack
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// DefaultAPIURL is the base URL of the Slack Web API.
+const DefaultAPIURL = "https://slack.com/api/"
+
+// maxSkew is the oldest request timestamp accepted, to prevent replayed events.
+const maxSkew = 5 * time.Minute
+
+// maxSeen is the number of recent event IDs remembered, to skip retried deliveries.
+const maxSeen = 1000
+
+// Config contains Slack settings.
+type Config struct {
+	// Token is the bot's OAuth token.
+	Token string
+	// SigningSecret verifies that events come from Slack. Leave empty to accept all events.
+	SigningSecret string
+	// Channel is the ID of the game channel.
+	Channel string
+	// APIURL defaults to DefaultAPIURL.
+	APIURL string
+	// Client defaults to http.DefaultClient.
+	Client *http.Client
+	// Listen, if set, is the address the Events API is served on while the Bot runs.
+	Listen string
+}
+
+// APIError is returned when Slack reports a failed API call.
+type APIError struct {
+	Method, Err string
+}
+
+func (e APIError) Error() string { return "slack: " + e.Method + ": " + e.Err }
+
+// Bot is a Slack bot, a chat.Transport for a single channel.
+type Bot struct {
+	cfg   Config
+	mu    sync.Mutex
+	recv  func(m chat.Message)
+	names map[string]string
+	dms   map[string]string
+	seen  map[string]bool
+	order []string
+}
+
+// NewBot creates a new Bot instance with settings cfg.
+func NewBot(cfg Config) *Bot {
+	if cfg.APIURL == "" {
+		cfg.APIURL = DefaultAPIURL
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &Bot{
+		cfg:   cfg,
+		names: make(map[string]string),
+		dms:   make(map[string]string),
+		seen:  make(map[string]bool),
+	}
+}
+
+// call Slack API method with form encoded args, decoding the response into res.
+func (b *Bot) call(method string, args url.Values, res interface{}) error {
+	var body = args.Encode()
+	var _, raw, err = chat.Retry(b.cfg.Client, func() (*http.Request, error) {
+		var req, err = http.NewRequest("POST", b.cfg.APIURL+method, strings.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+b.cfg.Token)
+		return req, nil
+	}, func(resp *http.Response, body []byte) time.Duration {
+		var s, _ = strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(s) * time.Second
+	})
+	if err != nil {
+		return err
+	}
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &r); err != nil {
+		return err
+	}
+	if !r.OK {
+		return &APIError{method, r.Error}
+	}
+	if res != nil {
+		return json.Unmarshal(raw, res)
+	}
+	return nil
+}
+
+// escape and unescape convert between plain text and Slack message formatting.
+var (
+	escape   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace
+	unescape = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">").Replace
+)
+
+// Post s to a Slack channel.
+func (b *Bot) Post(channel, s string) error {
+	return b.call("chat.postMessage", url.Values{"channel": {channel}, "text": {escape(s)}}, nil)
+}
+
+// dm returns the direct message channel for user, opening it if needed.
+func (b *Bot) dm(user string) (string, error) {
+	b.mu.Lock()
+	var ch, ok = b.dms[user]
+	b.mu.Unlock()
+	if ok {
+		return ch, nil
+	}
+	var res struct {
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+	}
+	if err := b.call("conversations.open", url.Values{"users": {user}}, &res); err != nil {
+		return "", err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dms[user] = res.Channel.ID
+	return res.Channel.ID, nil
+}
+
+// name returns the display name of user, looking it up if needed.
+func (b *Bot) name(user string) string {
+	b.mu.Lock()
+	var n, ok = b.names[user]
+	b.mu.Unlock()
+	if ok {
+		return n
+	}
+	var res struct {
+		User struct {
+			Name    string `json:"name"`
+			Profile struct {
+				DisplayName string `json:"display_name"`
+				RealName    string `json:"real_name"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := b.call("users.info", url.Values{"user": {user}}, &res); err != nil {
+		// Nb. the name is looked up again next time.
+		return user
+	}
+	for _, n = range []string{res.User.Profile.DisplayName, res.User.Profile.RealName, res.User.Name, user} {
+		if n != "" {
+			break
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.names[user] = n
+	return n
+}
+
+// Send text to the channel.
+func (b *Bot) Send(text string) error {
+	return b.Post(b.cfg.Channel, text)
+}
+
+// SendPrivate sends text to user by direct message.
+func (b *Bot) SendPrivate(user, text string) error {
+	var ch, err = b.dm(user)
+	if err != nil {
+		return err
+	}
+	return b.Post(ch, text)
+}
+
+/*
+Run passes messages posted in the channel to recv, until ctx is done.
+The Events API is served on Config.Listen, if set.
+*/
+func (b *Bot) Run(ctx context.Context, recv func(m chat.Message)) error {
+	b.mu.Lock()
+	b.recv = recv
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.recv = nil
+		b.mu.Unlock()
+	}()
+	if b.cfg.Listen == "" {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	var srv = &http.Server{Addr: b.cfg.Listen, Handler: b}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return ctx.Err()
+}
+
+// verify the Slack signature of an event request with body.
+func (b *Bot) verify(r *http.Request, body []byte) bool {
+	if b.cfg.SigningSecret == "" {
+		return true
+	}
+	var ts = r.Header.Get("X-Slack-Request-Timestamp")
+	var sec, err = strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if d := time.Since(time.Unix(sec, 0)); d > maxSkew || d < -maxSkew {
+		return false
+	}
+	return hmac.Equal([]byte(r.Header.Get("X-Slack-Signature")), []byte(sign(b.cfg.SigningSecret, ts, body)))
+}
+
+// sign returns the Slack signature of an event request with timestamp ts and body.
+func sign(secret, ts string, body []byte) string {
+	var h = hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("v0:" + ts + ":"))
+	h.Write(body)
+	return "v0=" + hex.EncodeToString(h.Sum(nil))
+}
+
+// Event is an Events API request payload, as far as the Bot needs it.
+type Event struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge,omitempty"`
+	EventID   string `json:"event_id,omitempty"`
+	Event     struct {
+		Type    string `json:"type"`
+		Subtype string `json:"subtype,omitempty"`
+		BotID   string `json:"bot_id,omitempty"`
+		Channel string `json:"channel"`
+		User    string `json:"user"`
+		Text    string `json:"text"`
+		TS      string `json:"ts"`
+	} `json:"event"`
+}
+
+// timestamp converts a Slack message ts to a time.
+func timestamp(ts string) time.Time {
+	var f, err = strconv.ParseFloat(ts, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Unix(0, int64(f*1e9))
+}
+
+// first reports whether event id is seen for the first time, remembering it.
+func (b *Bot) first(id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seen[id] {
+		return false
+	}
+	b.seen[id] = true
+	b.order = append(b.order, id)
+	if len(b.order) > maxSeen {
+		delete(b.seen, b.order[0])
+		b.order = b.order[1:]
+	}
+	return true
+}
+
+/*
+ServeHTTP handles Events API requests.
+Messages from users in the channel are acknowledged straight away, and passed on afterwards while the Bot is running,
+so that looking up the speaker's name, or the game's own replies, cannot hold up the acknowledgement past Slack's deadline.
+Retried deliveries of an event already received are acknowledged without being passed on again.
+*/
+func (b *Bot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body, err = io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !b.verify(r, body) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch ev.Type {
+	case "url_verification":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, ev.Challenge)
+		return
+	case "event_callback":
+		var m = ev.Event
+		if m.Type != "message" || m.Subtype != "" || m.BotID != "" || m.Channel != b.cfg.Channel {
+			break
+		}
+		b.mu.Lock()
+		var recv = b.recv
+		b.mu.Unlock()
+		if recv == nil || ev.EventID != "" && !b.first(ev.EventID) {
+			break
+		}
+		go func() {
+			recv(chat.Message{
+				Room: m.Channel, User: m.User, Name: b.name(m.User), Account: m.User, Time: timestamp(m.TS), Text: unescape(m.Text),
+			})
+		}()
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,86 @@
+package slack
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+	"github.com/joshringer/assassinbot/chat"
+	"github.com/joshringer/assassinbot/slack/slacktest"
+)
+
+func TestBot(t *testing.T) {
+	var srv = slacktest.NewServer("xoxb-test")
+	defer srv.Close()
+	srv.AddUser("U1", "Ace")
+	var talk = make(chan assassin.Talk, 1)
+	var b = NewBot(Config{Token: "xoxb-test", SigningSecret: "secret", Channel: "C1", APIURL: srv.APIURL()})
+	var br = chat.NewBridge(b, func(t assassin.Talk) { talk <- t })
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go br.Run(ctx)
+	for start := time.Now(); !b.running(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("Bot not running")
+		}
+	}
+	t.Run("verification", func(t *testing.T) {
+		var w = slacktest.SendEvent(b, "secret", []byte(`{"type":"url_verification","challenge":"abc"}`))
+		if w.Code != http.StatusOK || w.Body.String() != "abc" {
+			t.Error("Unexpected response", w.Code, w.Body)
+		}
+		if w = slacktest.SendEvent(b, "wrong", slacktest.MessageEvent("C1", "U1", "hi")); w.Code != http.StatusUnauthorized {
+			t.Error("Unexpected response to bad signature", w.Code)
+		}
+	})
+	t.Run("talk", func(t *testing.T) {
+		slacktest.SendEvent(b, "secret", slacktest.MessageEvent("C2", "U1", "elsewhere"))
+		slacktest.SendEvent(b, "secret", slacktest.MessageEvent("C1", "U1", "a < b"))
+		select {
+		case tk := <-talk:
+			if tk.Text != "a < b" || tk.Channel != "C1" || tk.Name != "Ace" || tk.Speaker != br.ID("U1") || tk.Time.IsZero() {
+				t.Error("Unexpected talk", tk)
+			}
+		case <-time.After(time.Second):
+			t.Error("Talk not passed on")
+		}
+		// a retried delivery of an event already received is not passed on again
+		var ev = slacktest.MessageEvent("C1", "U1", "again")
+		slacktest.SendEvent(b, "secret", ev)
+		if w := slacktest.SendEvent(b, "secret", ev); w.Code != http.StatusOK {
+			t.Error("Unexpected response to retry", w.Code)
+		}
+		if tk := <-talk; tk.Text != "again" {
+			t.Error("Unexpected talk", tk)
+		}
+		select {
+		case tk := <-talk:
+			t.Error("Unexpected talk", tk)
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+	t.Run("messages", func(t *testing.T) {
+		srv.RateLimit(1)
+		br.Announce("x > y")
+		br.Notify(assassin.Player{ID: br.ID("U1")}, "secret")
+		br.Notify(assassin.Player{ID: 99, Name: "Dee"}, "lost")
+		var ms = srv.Messages()
+		if len(ms) != 2 || ms[0] != (slacktest.Message{Channel: "C1", Text: "x &gt; y"}) || ms[1] != (slacktest.Message{Channel: slacktest.DM("U1"), Text: "secret"}) {
+			t.Error("Unexpected messages", ms)
+		}
+		if err := b.Post("", "nowhere"); err == nil {
+			t.Error("Expected error posting to no channel")
+		} else if e, ok := err.(*APIError); !ok || e.Err != "channel_not_found" {
+			t.Error("Unexpected error", err)
+		}
+	})
+}
+
+// running reports whether the Bot is passing messages on.
+func (b *Bot) running() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.recv != nil
+}
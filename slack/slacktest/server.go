@@ -0,0 +1,171 @@
+/*
+Package slacktest provides an in-process fake of the Slack Web API and Events API, for testing bots offline.
+
+The Server implements just enough of chat.postMessage, conversations.open and users.info to run a game,
+recording every message posted. Events are delivered to the bot under test with SendEvent.
+*/
+package slacktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a message posted to the Server.
+type Message struct {
+	Channel, Text string
+}
+
+// Server is a fake Slack API server.
+type Server struct {
+	*httptest.Server
+	token   string
+	mu      sync.Mutex
+	users   map[string]string
+	msgs    []Message
+	limited int
+}
+
+// NewServer starts a new Server, accepting API calls authorised by token. Close the Server when done.
+func NewServer(token string) *Server {
+	var s = &Server{token: token, users: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// APIURL returns the base URL of the Server's Web API.
+func (s *Server) APIURL() string {
+	return s.URL + "/api/"
+}
+
+// AddUser adds a user with given ID and display name.
+func (s *Server) AddUser(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[id] = name
+}
+
+// RateLimit rejects the next n API calls as rate limited.
+func (s *Server) RateLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited = n
+}
+
+// Messages returns the messages posted so far, in order.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+// DM returns the ID of the direct message channel with user.
+func DM(user string) string {
+	return "D" + user
+}
+
+func reply(w http.ResponseWriter, v map[string]interface{}) {
+	if _, ok := v["ok"]; !ok {
+		v["ok"] = true
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func fail(w http.ResponseWriter, err string) {
+	reply(w, map[string]interface{}{"ok": false, "error": err})
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limited > 0 {
+		s.limited--
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+s.token {
+		fail(w, "invalid_auth")
+		return
+	}
+	switch strings.TrimPrefix(r.URL.Path, "/api/") {
+	case "chat.postMessage":
+		var ch, text = r.FormValue("channel"), r.FormValue("text")
+		if ch == "" {
+			fail(w, "channel_not_found")
+			return
+		}
+		if text == "" {
+			fail(w, "no_text")
+			return
+		}
+		s.msgs = append(s.msgs, Message{ch, text})
+		reply(w, map[string]interface{}{"channel": ch, "ts": strconv.Itoa(len(s.msgs)) + ".000000"})
+	case "conversations.open":
+		var u = r.FormValue("users")
+		if _, ok := s.users[u]; !ok {
+			fail(w, "user_not_found")
+			return
+		}
+		reply(w, map[string]interface{}{"channel": map[string]string{"id": DM(u)}})
+	case "users.info":
+		var u = r.FormValue("user")
+		var n, ok = s.users[u]
+		if !ok {
+			fail(w, "user_not_found")
+			return
+		}
+		reply(w, map[string]interface{}{"user": map[string]interface{}{
+			"id":      u,
+			"name":    strings.ToLower(n),
+			"profile": map[string]string{"display_name": n, "real_name": n},
+		}})
+	default:
+		fail(w, "unknown_method")
+	}
+}
+
+// events counts the payloads made by MessageEvent, to give each its own event ID.
+var events int64
+
+// MessageEvent returns an Events API payload for a message from user in channel, escaping text as Slack does.
+func MessageEvent(channel, user, text string) []byte {
+	var ts = strconv.FormatFloat(float64(time.Now().UnixNano())/1e9, 'f', 6, 64)
+	var b, _ = json.Marshal(map[string]interface{}{
+		"type":     "event_callback",
+		"event_id": "Ev" + strconv.FormatInt(atomic.AddInt64(&events, 1), 10),
+		"event": map[string]string{
+			"type":    "message",
+			"channel": channel,
+			"user":    user,
+			"text":    strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text),
+			"ts":      ts,
+		},
+	})
+	return b
+}
+
+// SendEvent delivers an Events API payload to h, signed with secret as Slack would, returning the response.
+func SendEvent(h http.Handler, secret string, body []byte) *httptest.ResponseRecorder {
+	var ts = strconv.FormatInt(time.Now().Unix(), 10)
+	var m = hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("v0:" + ts + ":"))
+	m.Write(body)
+	var r = httptest.NewRequest("POST", "/slack/events", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Slack-Request-Timestamp", ts)
+	r.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(m.Sum(nil)))
+	var w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,279 @@
+/*
+Package tui is a hot-seat terminal front end, for playing a game on a single shared screen.
+
+Announcements are shown in a pane everyone can see. Notifications go to a pane for each player,
+hidden until its player reveals it, so players can take turns at the keyboard without seeing each other's KillWords.
+
+Keys:
+
+	Tab      pass the keyboard to the next player, hiding the private pane
+	Ctrl-R   reveal, or hide, the current player's private pane
+	Enter    say the typed line as the current player
+	Ctrl-C   quit
+
+The terminal must be in raw mode while the UI runs.
+
+Usage:
+
+	var ui = tui.New(players, nil)
+	var e = assassin.NewGameEngine(assassin.LangEn, ui, timing, nil)
+	ui.SetTalkHandler(func(t assassin.Talk) { t.Game = g.ID; e.IncomingTalk(t) })
+	go e.Run(g)
+	ui.Run(ctx, os.Stdin, os.Stdout)
+*/
+package tui
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// How many of the latest lines the shared and private panes show.
+const (
+	SharedLines  = 15
+	PrivateLines = 5
+)
+
+// Keys handled by the UI.
+const (
+	keyQuit      = 0x03
+	keyEOF       = 0x04
+	keyBackspace = 0x08
+	keyNext      = '\t'
+	keyReveal    = 0x12
+	keyEnter     = '\r'
+	keyEsc       = 0x1b
+	keyDelete    = 0x7f
+)
+
+// UI is a hot-seat terminal front end, and the MessageHandler for its game.
+type UI struct {
+	mu      sync.Mutex
+	out     io.Writer
+	talk    func(t assassin.Talk)
+	seats   []assassin.ID
+	names   map[assassin.ID]string
+	shared  []string
+	private map[assassin.ID][]string
+	unread  map[assassin.ID]int
+	seat    int
+	shown   bool
+	input   []rune
+}
+
+/*
+New creates a new UI instance for the named players, seated in order of ID.
+Lines typed are passed to talk, typically a GameEngine.IncomingTalk wrapper setting the Game.
+*/
+func New(players map[assassin.ID]string, talk func(t assassin.Talk)) *UI {
+	if talk == nil {
+		talk = func(t assassin.Talk) {}
+	}
+	var ui = &UI{
+		talk:    talk,
+		names:   make(map[assassin.ID]string, len(players)),
+		private: make(map[assassin.ID][]string, len(players)),
+		unread:  make(map[assassin.ID]int, len(players)),
+	}
+	for id, n := range players {
+		ui.seats = append(ui.seats, id)
+		ui.names[id] = n
+	}
+	sort.Slice(ui.seats, func(i, j int) bool { return ui.seats[i] < ui.seats[j] })
+	return ui
+}
+
+// SetTalkHandler sets the function that typed lines are passed to.
+func (ui *UI) SetTalkHandler(talk func(t assassin.Talk)) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.talk = talk
+}
+
+// current returns the player at the keyboard. The caller must hold ui.mu.
+func (ui *UI) current() assassin.ID {
+	if len(ui.seats) == 0 {
+		return 0
+	}
+	return ui.seats[ui.seat]
+}
+
+// Announce shows s in the shared pane.
+func (ui *UI) Announce(s string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.shared = append(ui.shared, s)
+	ui.render()
+}
+
+// Notify adds s to the private pane of p.
+func (ui *UI) Notify(p assassin.Player, s string) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	ui.private[p.ID] = append(ui.private[p.ID], s)
+	if !ui.shown || p.ID != ui.current() {
+		ui.unread[p.ID]++
+	}
+	ui.render()
+}
+
+// last returns the last n lines.
+func last(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// render redraws the screen. The caller must hold ui.mu.
+func (ui *UI) render() {
+	if ui.out == nil {
+		return
+	}
+	var b strings.Builder
+	var line = func(format string, a ...interface{}) {
+		fmt.Fprintf(&b, format, a...)
+		// Nb. raw mode turns off output processing, so lines need a carriage return.
+		b.WriteString("\x1b[K\r\n")
+	}
+	var rule = strings.Repeat("─", 60)
+	b.WriteString("\x1b[H\x1b[2J")
+	line("assassinbot hot-seat  (Tab: next player, Ctrl-R: reveal, Ctrl-C: quit)")
+	line("%v", rule)
+	for _, s := range last(ui.shared, SharedLines) {
+		line("%v", s)
+	}
+	line("%v", rule)
+	var id = ui.current()
+	switch {
+	case ui.shown:
+		line("Private to %v:", ui.names[id])
+		for _, s := range last(ui.private[id], PrivateLines) {
+			line("  %v", s)
+		}
+	case ui.unread[id] > 0:
+		line("Private to %v: hidden, %v new (Ctrl-R to reveal)", ui.names[id], ui.unread[id])
+	default:
+		line("Private to %v: hidden (Ctrl-R to reveal)", ui.names[id])
+	}
+	line("%v", rule)
+	var seats = make([]string, 0, len(ui.seats))
+	for i, s := range ui.seats {
+		var n = ui.names[s]
+		if ui.unread[s] > 0 {
+			n = fmt.Sprintf("%v(%v)", n, ui.unread[s])
+		}
+		if i == ui.seat {
+			n = "[" + n + "]"
+		}
+		seats = append(seats, n)
+	}
+	line("Players: %v", strings.Join(seats, " "))
+	fmt.Fprintf(&b, "%v> %v", ui.names[id], string(ui.input))
+	io.WriteString(ui.out, b.String())
+}
+
+/*
+key handles a single key press, reporting whether to carry on.
+Typed lines are returned rather than said, so they are passed on without holding the lock.
+*/
+func (ui *UI) key(r rune) (t *assassin.Talk, ok bool) {
+	ui.mu.Lock()
+	defer ui.mu.Unlock()
+	defer ui.render()
+	switch r {
+	case keyQuit, keyEOF:
+		return nil, false
+	case keyNext:
+		if len(ui.seats) > 0 {
+			ui.seat = (ui.seat + 1) % len(ui.seats)
+		}
+		ui.shown = false
+		ui.input = ui.input[:0]
+	case keyReveal:
+		if ui.shown = !ui.shown; ui.shown {
+			ui.unread[ui.current()] = 0
+		}
+	case keyEnter, '\n':
+		if len(ui.input) > 0 && len(ui.seats) > 0 {
+			var id = ui.current()
+			t = &assassin.Talk{Speaker: id, Name: ui.names[id], Time: time.Now(), Text: string(ui.input)}
+			ui.input = ui.input[:0]
+		}
+	case keyBackspace, keyDelete:
+		if len(ui.input) > 0 {
+			ui.input = ui.input[:len(ui.input)-1]
+		}
+	default:
+		if r >= ' ' {
+			ui.input = append(ui.input, r)
+		}
+	}
+	return t, true
+}
+
+/*
+Run the UI on terminal in and out, until Ctrl-C is pressed, in ends, or ctx is done.
+Escape sequences, such as arrow keys, are ignored.
+*/
+func (ui *UI) Run(ctx context.Context, in io.Reader, out io.Writer) error {
+	ui.mu.Lock()
+	ui.out = out
+	ui.render()
+	ui.mu.Unlock()
+	var keys = make(chan rune)
+	var done = make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(keys)
+		var r = bufio.NewReader(in)
+		for {
+			var k, _, err = r.ReadRune()
+			if err != nil {
+				return
+			}
+			if k == keyEsc {
+				// skip to the end of the sequence, a letter or ~
+				for {
+					if k, _, err = r.ReadRune(); err != nil || k != '[' && k != 'O' && (k >= 'A' && k <= 'Z' || k >= 'a' && k <= 'z' || k == '~') {
+						break
+					}
+				}
+				continue
+			}
+			select {
+			case keys <- k:
+			case <-done:
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			var t, more = ui.key(k)
+			if !more {
+				return nil
+			}
+			if t != nil {
+				ui.mu.Lock()
+				var talk = ui.talk
+				ui.mu.Unlock()
+				talk(*t)
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/joshringer/assassinbot/assassin"
+)
+
+// screen returns the last screen drawn to out.
+func screen(out *bytes.Buffer) string {
+	var s = out.String()
+	return s[strings.LastIndex(s, "\x1b[2J"):]
+}
+
+func TestUI(t *testing.T) {
+	var talk []assassin.Talk
+	var ui = New(map[assassin.ID]string{2: "bob", 1: "alice"}, func(t assassin.Talk) { talk = append(talk, t) })
+	ui.Announce("The game has begun.")
+	ui.Notify(assassin.Player{ID: 1, Name: "alice"}, "Your target is bob. Your KillWord is apple.")
+	ui.Notify(assassin.Player{ID: 2, Name: "bob"}, "Your target is alice. Your KillWord is pear.")
+	var out bytes.Buffer
+	var run = func(keys string) {
+		if err := ui.Run(context.Background(), strings.NewReader(keys), &out); err != nil {
+			t.Error(err)
+		}
+	}
+	run("")
+	var s = screen(&out)
+	if !strings.Contains(s, "The game has begun.") || !strings.Contains(s, "Players: [alice(1)] bob(1)") || strings.Contains(s, "KillWord") {
+		t.Error("Unexpected screen", s)
+	}
+	t.Run("Talk", func(t *testing.T) {
+		run("hi bobx\x7f\x1b[Db\r\r")
+		if len(talk) != 1 || talk[0].Speaker != 1 || talk[0].Name != "alice" || talk[0].Text != "hi bobb" {
+			t.Error("Unexpected talk", talk)
+		}
+	})
+	t.Run("Reveal", func(t *testing.T) {
+		run("\x12")
+		if s := screen(&out); !strings.Contains(s, "apple") || strings.Contains(s, "pear") || !strings.Contains(s, "Players: [alice] bob(1)") {
+			t.Error("Unexpected screen", s)
+		}
+		run("\tbo")
+		if s := screen(&out); strings.Contains(s, "apple") || strings.Contains(s, "pear") || !strings.HasSuffix(s, "bob> bo") {
+			t.Error("Unexpected screen", s)
+		}
+		run("\x12")
+		if s := screen(&out); !strings.Contains(s, "pear") || strings.Contains(s, "apple") {
+			t.Error("Unexpected screen", s)
+		}
+	})
+	t.Run("Quit", func(t *testing.T) {
+		run("\x03x\r")
+		if len(talk) != 1 {
+			t.Error("Unexpected talk", talk)
+		}
+	})
+}
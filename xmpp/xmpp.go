@@ -0,0 +1,412 @@
+/*
+Package xmpp is a chat.Transport for an XMPP multi-user chat room.
+
+The Transport logs in with SASL PLAIN, over STARTTLS whenever the server offers it, and joins the room.
+Players are identified by their nick in the room, and private messages are sent through the room.
+In rooms that show occupants' real JIDs to the bot, each message's Account is the speaker's bare JID.
+Room history replayed on joining is ignored.
+
+Usage:
+
+	var t = xmpp.New(xmpp.Config{JID: "bot@example.org", Password: pw, Room: "game@conference.example.org"})
+	var b = chat.NewBridge(t, engine.IncomingTalk)
+	go b.Run(ctx)
+*/
+package xmpp
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// XML namespaces.
+const (
+	nsStream  = "http://etherx.jabber.org/streams"
+	nsTLS     = "urn:ietf:params:xml:ns:xmpp-tls"
+	nsSASL    = "urn:ietf:params:xml:ns:xmpp-sasl"
+	nsBind    = "urn:ietf:params:xml:ns:xmpp-bind"
+	nsMUC     = "http://jabber.org/protocol/muc"
+	nsMUCUser = "http://jabber.org/protocol/muc#user"
+)
+
+// Config contains XMPP settings. Zero values are replaced by defaults.
+type Config struct {
+	// JID is the bot's account, Password its password.
+	JID, Password string
+	// Addr is the server's host:port, defaulting to port 5222 of the JID's domain.
+	Addr string
+	// TLS is used for STARTTLS, defaulting to verifying the JID's domain.
+	TLS *tls.Config
+	// AllowPlaintext allows logging in to servers that do not offer STARTTLS.
+	AllowPlaintext bool
+	// Room is the address of the game room, Nick the bot's nick there.
+	Room, Nick string
+	// Reconnects are delayed by Backoff, doubling after each failed attempt up to MaxBackoff.
+	Backoff, MaxBackoff time.Duration
+}
+
+// DefaultConfig contains the default settings for Config.
+var DefaultConfig = Config{
+	Nick:       "assassinbot",
+	Backoff:    time.Second,
+	MaxBackoff: 5 * time.Minute,
+}
+
+var (
+	// ErrNoTLS is returned when the server does not offer STARTTLS, and AllowPlaintext is not set.
+	ErrNoTLS = errors.New("xmpp: server does not offer TLS")
+	// ErrAuth is returned when the server rejects the bot's login.
+	ErrAuth = errors.New("xmpp: authentication failed")
+	// ErrNotConnected is returned when sending a message while disconnected.
+	ErrNotConnected = errors.New("xmpp: not connected")
+)
+
+// Transport is a connection to an XMPP room.
+type Transport struct {
+	cfg          Config
+	user, domain string
+	mu           sync.Mutex
+	s            *stream
+	jids         map[string]string
+}
+
+// New creates a new Transport instance with settings cfg.
+func New(cfg Config) *Transport {
+	var d = DefaultConfig
+	if cfg.Nick == "" {
+		cfg.Nick = d.Nick
+	}
+	if cfg.Backoff == 0 {
+		cfg.Backoff = d.Backoff
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = d.MaxBackoff
+	}
+	var user, domain = "", cfg.JID
+	if i := strings.IndexByte(cfg.JID, '@'); i >= 0 {
+		user, domain = cfg.JID[:i], cfg.JID[i+1:]
+	}
+	if i := strings.IndexByte(domain, '/'); i >= 0 {
+		domain = domain[:i]
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = net.JoinHostPort(domain, "5222")
+	}
+	if cfg.TLS == nil {
+		cfg.TLS = &tls.Config{ServerName: domain}
+	}
+	return &Transport{cfg: cfg, user: user, domain: domain, jids: make(map[string]string)}
+}
+
+// stream is an XML stream over a connection.
+type stream struct {
+	conn net.Conn
+	r    *bufio.Reader
+	d    *xml.Decoder
+	mu   sync.Mutex
+}
+
+func newStream(conn net.Conn) *stream {
+	var r = bufio.NewReader(conn)
+	return &stream{conn: conn, r: r, d: xml.NewDecoder(r)}
+}
+
+// esc escapes s for XML text or attribute values.
+func esc(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// send writes raw XML to the stream.
+func (s *stream) send(format string, a ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(time.Minute))
+	var _, err = fmt.Fprintf(s.conn, format, a...)
+	return err
+}
+
+// next returns the next top level element of the stream, returning io.EOF when the stream ends.
+func (s *stream) next() (xml.StartElement, error) {
+	for {
+		var tok, err = s.d.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			if t.Name.Space == nsStream && t.Name.Local == "stream" {
+				return xml.StartElement{}, io.EOF
+			}
+		}
+	}
+}
+
+// features are the stream features offered by the server.
+type features struct {
+	StartTLS   *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-tls starttls"`
+	Mechanisms []string  `xml:"urn:ietf:params:xml:ns:xmpp-sasl mechanisms>mechanism"`
+	Bind       *struct{} `xml:"urn:ietf:params:xml:ns:xmpp-bind bind"`
+}
+
+// open a new stream to the server, returning the features offered.
+func (t *Transport) open(s *stream) (*features, error) {
+	s.d = xml.NewDecoder(s.r)
+	if err := s.send("<stream:stream to='%s' xmlns='jabber:client' xmlns:stream='%s' version='1.0'>", esc(t.domain), nsStream); err != nil {
+		return nil, err
+	}
+	var el, err = s.next()
+	if err != nil {
+		return nil, err
+	}
+	if el.Name.Space != nsStream || el.Name.Local != "stream" {
+		return nil, fmt.Errorf("xmpp: unexpected %v", el.Name.Local)
+	}
+	if el, err = s.next(); err != nil {
+		return nil, err
+	}
+	var f features
+	if el.Name.Space != nsStream || el.Name.Local != "features" {
+		return nil, fmt.Errorf("xmpp: unexpected %v", el.Name.Local)
+	}
+	return &f, s.d.DecodeElement(&f, &el)
+}
+
+// login negotiates TLS, authenticates and binds a resource, returning the stream ready for stanzas.
+func (t *Transport) login(ctx context.Context) (*stream, error) {
+	var d net.Dialer
+	var conn, err = d.DialContext(ctx, "tcp", t.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	var s = newStream(conn)
+	var fail = func(err error) (*stream, error) {
+		conn.Close()
+		return nil, err
+	}
+	f, err := t.open(s)
+	if err != nil {
+		return fail(err)
+	}
+	if f.StartTLS != nil {
+		s.send("<starttls xmlns='%s'/>", nsTLS)
+		if el, err := s.next(); err != nil {
+			return fail(err)
+		} else if el.Name.Local != "proceed" {
+			return fail(ErrNoTLS)
+		}
+		var tc = tls.Client(conn, t.cfg.TLS)
+		if err := tc.HandshakeContext(ctx); err != nil {
+			return fail(err)
+		}
+		s = newStream(tc)
+		if f, err = t.open(s); err != nil {
+			return fail(err)
+		}
+	} else if !t.cfg.AllowPlaintext {
+		return fail(ErrNoTLS)
+	}
+	var plain bool
+	for _, m := range f.Mechanisms {
+		plain = plain || m == "PLAIN"
+	}
+	if !plain {
+		return fail(ErrAuth)
+	}
+	s.send("<auth xmlns='%s' mechanism='PLAIN'>%s</auth>", nsSASL,
+		base64.StdEncoding.EncodeToString([]byte("\x00"+t.user+"\x00"+t.cfg.Password)))
+	if el, err := s.next(); err != nil {
+		return fail(err)
+	} else if el.Name.Local != "success" {
+		return fail(ErrAuth)
+	}
+	if f, err = t.open(s); err != nil {
+		return fail(err)
+	}
+	if f.Bind == nil {
+		return fail(errors.New("xmpp: server does not offer resource binding"))
+	}
+	s.send("<iq type='set' id='bind'><bind xmlns='%s'><resource>%s</resource></bind></iq>", nsBind, esc(t.cfg.Nick))
+	for {
+		var el, err = s.next()
+		if err != nil {
+			return fail(err)
+		}
+		var st stanza
+		if err := s.d.DecodeElement(&st, &el); err != nil {
+			return fail(err)
+		}
+		if el.Name.Local == "iq" && st.ID == "bind" {
+			if st.Type != "result" {
+				return fail(errors.New("xmpp: resource binding failed"))
+			}
+			return s, nil
+		}
+	}
+}
+
+// stanza is a message, presence or iq stanza, as far as the Transport needs it.
+type stanza struct {
+	Type  string    `xml:"type,attr"`
+	From  string    `xml:"from,attr"`
+	To    string    `xml:"to,attr"`
+	ID    string    `xml:"id,attr"`
+	Body  string    `xml:"body"`
+	Delay *struct{} `xml:"urn:xmpp:delay delay"`
+	Ping  *struct{} `xml:"urn:xmpp:ping ping"`
+	User  *struct {
+		Item struct {
+			JID string `xml:"jid,attr"`
+		} `xml:"item"`
+	} `xml:"http://jabber.org/protocol/muc#user x"`
+}
+
+// bare returns jid without its resource.
+func bare(jid string) string {
+	if i := strings.IndexByte(jid, '/'); i >= 0 {
+		return jid[:i]
+	}
+	return jid
+}
+
+/*
+Run connects to the server, joins the room and passes messages said there to recv, until ctx is done.
+Whenever the connection is lost, Run reconnects after a backoff delay.
+Failed logins are not retried.
+*/
+func (t *Transport) Run(ctx context.Context, recv func(m chat.Message)) error {
+	var wait = t.cfg.Backoff
+	for {
+		var ok, err = t.session(ctx, recv)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == ErrAuth || err == ErrNoTLS {
+			return err
+		}
+		if ok {
+			wait = t.cfg.Backoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if wait *= 2; wait > t.cfg.MaxBackoff {
+			wait = t.cfg.MaxBackoff
+		}
+	}
+}
+
+/*
+session runs a single connection to the server, returning once it is closed.
+Reports whether the Transport joined the room.
+*/
+func (t *Transport) session(ctx context.Context, recv func(m chat.Message)) (bool, error) {
+	var s, err = t.login(ctx)
+	if err != nil {
+		return false, err
+	}
+	var done = make(chan struct{})
+	defer close(done)
+	defer s.conn.Close()
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.send("</stream:stream>")
+			s.conn.Close()
+		case <-done:
+		}
+	}()
+	var self = t.cfg.Room + "/" + t.cfg.Nick
+	s.send("<presence to='%s'><x xmlns='%s'><history maxstanzas='0'/></x></presence>", esc(self), nsMUC)
+	t.mu.Lock()
+	t.s = s
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.s = nil
+	}()
+	var joined bool
+	for {
+		var el, err = s.next()
+		if err != nil {
+			return joined, err
+		}
+		var st stanza
+		if err := s.d.DecodeElement(&st, &el); err != nil {
+			return joined, err
+		}
+		switch el.Name.Local {
+		case "presence":
+			if st.From == self {
+				if st.Type == "error" {
+					return joined, fmt.Errorf("xmpp: could not join %v", t.cfg.Room)
+				}
+				joined = true
+			}
+			var nick = strings.TrimPrefix(st.From, t.cfg.Room+"/")
+			t.mu.Lock()
+			if st.Type == "unavailable" || st.User == nil || st.User.Item.JID == "" {
+				delete(t.jids, nick)
+			} else {
+				t.jids[nick] = bare(st.User.Item.JID)
+			}
+			t.mu.Unlock()
+		case "message":
+			var nick = strings.TrimPrefix(st.From, t.cfg.Room+"/")
+			if st.Type != "groupchat" || nick == st.From || nick == t.cfg.Nick || st.Delay != nil || st.Body == "" {
+				continue
+			}
+			t.mu.Lock()
+			var jid = t.jids[nick]
+			t.mu.Unlock()
+			recv(chat.Message{Room: t.cfg.Room, User: nick, Name: nick, Account: jid, Time: time.Now(), Text: st.Body})
+		case "iq":
+			switch {
+			case st.Type == "get" && st.Ping != nil:
+				s.send("<iq type='result' to='%s' id='%s'/>", esc(st.From), esc(st.ID))
+			case st.Type == "get" || st.Type == "set":
+				s.send("<iq type='error' to='%s' id='%s'><error type='cancel'><service-unavailable xmlns='urn:ietf:params:xml:ns:xmpp-stanzas'/></error></iq>",
+					esc(st.From), esc(st.ID))
+			}
+		}
+	}
+}
+
+// message sends a message stanza of type typ with body text.
+func (t *Transport) message(to, typ, text string) error {
+	t.mu.Lock()
+	var s = t.s
+	t.mu.Unlock()
+	if s == nil {
+		return ErrNotConnected
+	}
+	return s.send("<message to='%s' type='%s'><body>%s</body></message>", esc(to), typ, esc(text))
+}
+
+// Send text to the room.
+func (t *Transport) Send(text string) error {
+	return t.message(t.cfg.Room, "groupchat", text)
+}
+
+// SendPrivate sends text privately to the occupant of the room with nick user.
+func (t *Transport) SendPrivate(user, text string) error {
+	return t.message(t.cfg.Room+"/"+user, "chat", text)
+}
@@ -0,0 +1,206 @@
+package xmpp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joshringer/assassinbot/chat"
+)
+
+// element is any stanza read by the fake server.
+type element struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+func (e element) attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// fakeConn is the server end of a client connection.
+type fakeConn struct {
+	t    *testing.T
+	conn net.Conn
+	d    *xml.Decoder
+}
+
+// read the next stream start or stanza from the client, or an empty element once it disconnects.
+func (c *fakeConn) read() element {
+	for {
+		var tok, err = c.d.Token()
+		if err != nil {
+			return element{}
+		}
+		if s, ok := tok.(xml.StartElement); ok {
+			if s.Name.Local == "stream" {
+				return element{XMLName: s.Name, Attrs: s.Attr}
+			}
+			var e element
+			c.d.DecodeElement(&e, &s)
+			return e
+		}
+	}
+}
+
+func (c *fakeConn) write(s string) {
+	c.conn.Write([]byte(s))
+}
+
+// open answers a stream start with features.
+func (c *fakeConn) open(features string) {
+	if e := c.read(); e.XMLName.Local != "stream" || e.attr("to") != "example.org" {
+		c.t.Error("Unexpected stream", e)
+	}
+	c.write("<stream:stream xmlns='jabber:client' xmlns:stream='" + nsStream + "' version='1.0'>")
+	c.write("<stream:features>" + features + "</stream:features>")
+}
+
+// login runs the server side of a plaintext login.
+func (c *fakeConn) login(pass string) bool {
+	c.open("<mechanisms xmlns='" + nsSASL + "'><mechanism>PLAIN</mechanism></mechanisms>")
+	var auth = c.read()
+	if b, _ := base64.StdEncoding.DecodeString(auth.Inner); string(b) != "\x00bot\x00"+pass {
+		c.write("<failure xmlns='" + nsSASL + "'><not-authorized/></failure></stream:stream>")
+		return false
+	}
+	c.write("<success xmlns='" + nsSASL + "'/>")
+	c.open("<bind xmlns='" + nsBind + "'/>")
+	if e := c.read(); e.XMLName.Local != "iq" || !strings.Contains(e.Inner, "<resource>Bot</resource>") {
+		c.t.Error("Unexpected bind", e)
+	}
+	c.write("<iq type='result' id='bind'><bind xmlns='" + nsBind + "'><jid>bot@example.org/Bot</jid></bind></iq>")
+	if e := c.read(); e.XMLName.Local != "presence" || e.attr("to") != "game@muc.example.org/Bot" {
+		c.t.Error("Unexpected join", e)
+	}
+	c.write("<presence from='game@muc.example.org/Bot'/>")
+	return true
+}
+
+// listen accepts connections to a fake server, passing each to serve.
+func listen(t *testing.T, serve func(c *fakeConn)) net.Listener {
+	var l, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			var conn, err = l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(&fakeConn{t: t, conn: conn, d: xml.NewDecoder(conn)})
+			}()
+		}
+	}()
+	return l
+}
+
+func TestTransport(t *testing.T) {
+	var sent = make(chan element, 10)
+	var conns = make(chan *fakeConn, 2)
+	var l = listen(t, func(c *fakeConn) {
+		if !c.login("secret") {
+			return
+		}
+		conns <- c
+		for {
+			var e = c.read()
+			if e.XMLName.Local == "" {
+				return
+			}
+			sent <- e
+		}
+	})
+	defer l.Close()
+	var cfg = Config{
+		JID: "bot@example.org", Password: "secret", Addr: l.Addr().String(), AllowPlaintext: true,
+		Room: "game@muc.example.org", Nick: "Bot", Backoff: time.Millisecond,
+	}
+	var tr = New(cfg)
+	var recv = make(chan chat.Message, 10)
+	var ctx, cancel = context.WithCancel(context.Background())
+	var res = make(chan error)
+	go func() { res <- tr.Run(ctx, func(m chat.Message) { recv <- m }) }()
+	var c = <-conns
+	c.write("<message type='groupchat' from='game@muc.example.org/Ace'><body>old</body><delay xmlns='urn:xmpp:delay' stamp='2020-01-01T00:00:00Z'/></message>")
+	c.write("<message type='groupchat' from='game@muc.example.org/Bot'><body>own</body></message>")
+	c.write("<message type='groupchat' from='game@muc.example.org/Ace'><subject>topic</subject></message>")
+	c.write("<presence from='game@muc.example.org/Ace'><x xmlns='" + nsMUCUser + "'><item jid='ace@example.org/phone'/></x></presence>")
+	c.write("<message type='groupchat' from='game@muc.example.org/Ace'><body>hello &amp; kw</body></message>")
+	c.write("<iq type='get' from='example.org' id='p1'><ping xmlns='urn:xmpp:ping'/></iq>")
+	select {
+	case m := <-recv:
+		if m.Text != "hello & kw" || m.User != "Ace" || m.Name != "Ace" || m.Account != "ace@example.org" || m.Room != "game@muc.example.org" || m.Time.IsZero() {
+			t.Error("Unexpected message", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Message not received")
+	}
+	if e := <-sent; e.XMLName.Local != "iq" || e.attr("type") != "result" || e.attr("id") != "p1" {
+		t.Error("Unexpected ping reply", e)
+	}
+	if len(recv) != 0 {
+		t.Error("Unexpected message", <-recv)
+	}
+	t.Run("Send", func(t *testing.T) {
+		if err := tr.Send("The game has <begun>."); err != nil {
+			t.Error(err)
+		}
+		if err := tr.SendPrivate("Ace", "secret"); err != nil {
+			t.Error(err)
+		}
+		var room, priv = <-sent, <-sent
+		if room.attr("to") != "game@muc.example.org" || room.attr("type") != "groupchat" || room.Inner != "<body>The game has &lt;begun&gt;.</body>" {
+			t.Error("Unexpected message", room)
+		}
+		if priv.attr("to") != "game@muc.example.org/Ace" || priv.attr("type") != "chat" || priv.Inner != "<body>secret</body>" {
+			t.Error("Unexpected message", priv)
+		}
+	})
+	t.Run("Reconnect", func(t *testing.T) {
+		c.conn.Close()
+		select {
+		case c = <-conns:
+		case <-time.After(time.Second):
+			t.Fatal("Not reconnected")
+		}
+		c.write("<message type='groupchat' from='game@muc.example.org/Ace'><body>back</body></message>")
+		if m := <-recv; m.Text != "back" {
+			t.Error("Unexpected message", m)
+		}
+	})
+	cancel()
+	if err := <-res; err != context.Canceled {
+		t.Error("Unexpected error", err)
+	}
+	if err := tr.Send("late"); err != ErrNotConnected {
+		t.Error("Unexpected error", err)
+	}
+	t.Run("BadPassword", func(t *testing.T) {
+		var cfg = cfg
+		cfg.Password = "wrong"
+		if err := New(cfg).Run(context.Background(), nil); err != ErrAuth {
+			t.Error("Unexpected error", err)
+		}
+	})
+	t.Run("NoTLS", func(t *testing.T) {
+		var cfg = cfg
+		cfg.AllowPlaintext = false
+		if err := New(cfg).Run(context.Background(), nil); err != ErrNoTLS {
+			t.Error("Unexpected error", err)
+		}
+	})
+}